@@ -7,6 +7,7 @@ toolchain go1.21.6
 require (
 	github.com/grafana/grafana-plugin-sdk-go v0.211.0
 	github.com/stretchr/testify v1.8.4
+	go.etcd.io/bbolt v1.3.10
 )
 
 require (
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type noCacheKey struct{}
+
+// NoCache returns a context whose requests skip cached responses and always
+// go to Netlify. The fresh responses still refresh the cache.
+func NoCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+func isNoCache(ctx context.Context) bool {
+	noCache, _ := ctx.Value(noCacheKey{}).(bool)
+	return noCache
+}
+
+// cacheTTL converts a TTL setting in seconds, non positive values disable
+// caching.
+func cacheTTL(seconds int) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// cacheKey identifies a response by url and a hash of the token it was
+// requested with, so responses are never shared across tokens.
+func cacheKey(url string, accessToken string) string {
+	hash := sha256.Sum256([]byte(accessToken))
+
+	return hex.EncodeToString(hash[:8]) + " " + url
+}
+
+type cacheEntry struct {
+	key       string
+	body      []byte
+	header    http.Header
+	storedAt  time.Time
+	expiresAt time.Time
+}
+
+// CacheStats counts how requests going through the cache were answered.
+type CacheStats struct {
+	// Hits were served from a fresh cache entry.
+	Hits int64 `json:"hits"`
+	// Misses had to be fetched from Netlify.
+	Misses int64 `json:"misses"`
+	// NotModified were conditional requests Netlify answered with a 304, the
+	// cached body was reused.
+	NotModified int64 `json:"notModified"`
+	// Modified were conditional requests that returned a new body.
+	Modified int64 `json:"modified"`
+}
+
+// responseCache is a size bounded LRU cache of successful response bodies.
+// Expired entries are kept until evicted so their ETag can be revalidated.
+type responseCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+
+	hits        atomic.Int64
+	misses      atomic.Int64
+	notModified atomic.Int64
+	modified    atomic.Int64
+}
+
+func newResponseCache(maxEntries int) *responseCache {
+	return &responseCache{
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+// get returns the entry stored for key as long as it has not expired.
+func (c *responseCache) get(key string) (cacheEntry, bool) {
+	entry, ok := c.stale(key)
+	if !ok || time.Now().After(entry.expiresAt) {
+		return cacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+// stale returns the entry stored for key even if it has expired.
+func (c *responseCache) stale(key string) (cacheEntry, bool) {
+	if c == nil {
+		return cacheEntry{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+
+	c.order.MoveToFront(element)
+
+	return *element.Value.(*cacheEntry), true
+}
+
+func (c *responseCache) set(key string, body []byte, header http.Header, ttl time.Duration) {
+	if c == nil || c.maxEntries <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entry := &cacheEntry{
+		key:       key,
+		body:      body,
+		header:    header,
+		storedAt:  now,
+		expiresAt: now.Add(ttl),
+	}
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// stats returns a snapshot of the cache counters.
+func (c *responseCache) stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+
+	return CacheStats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		NotModified: c.notModified.Load(),
+		Modified:    c.modified.Load(),
+	}
+}
+
+type cacheOutcome int
+
+const (
+	cacheHit cacheOutcome = iota
+	cacheMiss
+	cacheNotModified
+	cacheModified
+)
+
+// record counts how a request was answered.
+func (c *responseCache) record(outcome cacheOutcome) {
+	if c == nil {
+		return
+	}
+
+	switch outcome {
+	case cacheHit:
+		c.hits.Add(1)
+	case cacheMiss:
+		c.misses.Add(1)
+	case cacheNotModified:
+		c.notModified.Add(1)
+	case cacheModified:
+		c.modified.Add(1)
+	}
+}
+
+// clear drops every cached response.
+func (c *responseCache) clear() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = map[string]*list.Element{}
+	c.order.Init()
+}
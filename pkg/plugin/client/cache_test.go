@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+func TestResponseCache(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		fmt.Fprint(w, `[{"id":"my-site"}]`)
+	}))
+	defer server.Close()
+
+	settings := models.Settings{
+		BaseUrl:     server.URL,
+		AccessToken: "my-token",
+		CacheTTL:    models.CacheTTL{Sites: 60},
+		CacheSize:   10,
+	}
+	c := newTestClient(t, settings)
+	ctx := context.Background()
+
+	_, meta, err := c.GetSites(ctx)
+	require.NoError(t, err)
+	assert.False(t, meta.Cached)
+
+	sites, meta, err := c.GetSites(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "my-site", sites[0].ID)
+	assert.True(t, meta.Cached)
+	assert.False(t, meta.CachedAt.IsZero())
+	assert.Equal(t, int32(1), calls.Load())
+
+	t.Run("bypassed with NoCache", func(t *testing.T) {
+		_, meta, err := c.GetSites(NoCache(ctx))
+		require.NoError(t, err)
+		assert.False(t, meta.Cached)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("keyed by token", func(t *testing.T) {
+		other := c
+		other.AccessToken = "other-token"
+
+		_, meta, err := other.GetSites(ctx)
+		require.NoError(t, err)
+		assert.False(t, meta.Cached)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("disabled for entities without ttl", func(t *testing.T) {
+		_, _, err := c.GetAccounts(ctx)
+		require.NoError(t, err)
+		_, meta, err := c.GetAccounts(ctx)
+		require.NoError(t, err)
+		assert.False(t, meta.Cached)
+		assert.Equal(t, int32(5), calls.Load())
+	})
+
+	t.Run("cleared", func(t *testing.T) {
+		c.ClearCache()
+
+		_, meta, err := c.GetSites(ctx)
+		require.NoError(t, err)
+		assert.False(t, meta.Cached)
+		assert.Equal(t, int32(6), calls.Load())
+	})
+}
+
+func TestResponseCacheBounds(t *testing.T) {
+	cache := newResponseCache(2)
+
+	cache.set("a", []byte("a"), nil, time.Minute)
+	cache.set("b", []byte("b"), nil, time.Minute)
+	_, ok := cache.get("a")
+	require.True(t, ok)
+
+	// b is now the least recently used entry
+	cache.set("c", []byte("c"), nil, time.Minute)
+
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	_, ok = cache.get("a")
+	assert.True(t, ok)
+	_, ok = cache.get("c")
+	assert.True(t, ok)
+
+	cache.set("expired", []byte("expired"), nil, -time.Second)
+	_, ok = cache.get("expired")
+	assert.False(t, ok)
+}
+
+func TestConditionalRequests(t *testing.T) {
+	var calls atomic.Int32
+	etag := `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		fmt.Fprintf(w, `[{"id":%s}]`, etag)
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, models.Settings{
+		BaseUrl:   server.URL,
+		CacheTTL:  models.CacheTTL{Sites: 1},
+		CacheSize: 10,
+	})
+	ctx := context.Background()
+	expire := func() {
+		c.cache.mu.Lock()
+		defer c.cache.mu.Unlock()
+		for _, element := range c.cache.entries {
+			element.Value.(*cacheEntry).expiresAt = time.Now().Add(-time.Second)
+		}
+	}
+
+	_, _, err := c.GetSites(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, CacheStats{Misses: 1}, c.CacheStats())
+
+	_, _, err = c.GetSites(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1}, c.CacheStats())
+
+	expire()
+	sites, meta, err := c.GetSites(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", sites[0].ID)
+	assert.False(t, meta.Cached)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1, NotModified: 1}, c.CacheStats())
+	assert.Equal(t, int32(2), calls.Load())
+
+	// the revalidated entry is fresh again
+	_, meta, err = c.GetSites(ctx)
+	require.NoError(t, err)
+	assert.True(t, meta.Cached)
+
+	expire()
+	etag = `"v2"`
+	sites, _, err = c.GetSites(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", sites[0].ID)
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 1, NotModified: 1, Modified: 1}, c.CacheStats())
+}
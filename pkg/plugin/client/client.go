@@ -6,146 +6,448 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
 	"github.com/grafana/netlify-datasource/pkg/plugin/models"
 )
 
 type Client struct {
 	models.Settings
-	client *http.Client
+	client    *http.Client
+	rateLimit *rateLimiter
+	cache     *responseCache
+	flights   *flightGroup
 }
 
-func NewClient(settings models.Settings) Client {
+// NewClient builds the Netlify client on top of the SDK http client provider,
+// so the proxy, TLS, timeout and custom header options configured for the
+// data source apply to every request. The Netlify access token is added by
+// a middleware.
+func NewClient(settings models.Settings, opts httpclient.Options) (Client, error) {
 	c := Client{}
 
-	c.client = &http.Client{}
-	c.BaseUrl = settings.BaseUrl
-	c.AccessToken = settings.AccessToken
-	c.AccountId = settings.AccountId
-	c.SiteId = settings.SiteId
+	if settings.RequestTimeout > 0 {
+		timeouts := httpclient.DefaultTimeoutOptions
+		if opts.Timeouts != nil {
+			timeouts = *opts.Timeouts
+		}
+		timeouts.Timeout = time.Duration(settings.RequestTimeout) * time.Second
+		opts.Timeouts = &timeouts
+	}
 
-	return c
+	if opts.Middlewares == nil {
+		opts.Middlewares = httpclient.DefaultMiddlewares()
+	}
+	opts.Middlewares = append(opts.Middlewares, bearerAuthMiddleware(settings.AccessToken))
+
+	httpClient, err := httpclient.NewProvider().New(opts)
+	if err != nil {
+		return c, fmt.Errorf("failed to create http client: %w", err)
+	}
+
+	c.client = httpClient
+	c.rateLimit = newRateLimiter()
+	c.cache = newResponseCache(settings.CacheSize)
+	c.flights = newFlightGroup()
+	c.Settings = settings
+
+	return c, nil
 }
 
-type ErrorResponse struct {
-	Code    int64  `json:"code"`
-	Message string `json:"message"`
+// bearerAuthMiddleware authenticates requests with the Netlify access token.
+func bearerAuthMiddleware(accessToken string) httpclient.Middleware {
+	return httpclient.NamedMiddlewareFunc("netlify-bearer-auth", func(opts httpclient.Options, next http.RoundTripper) http.RoundTripper {
+		return httpclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+			return next.RoundTrip(req)
+		})
+	})
 }
 
-func (c Client) doGet(url string, response any) error {
-	req, err := http.NewRequest("GET", url, nil)
+func (c Client) doGet(ctx context.Context, url string, ttl time.Duration, response any) (Meta, error) {
+	res, err := c.get(ctx, url, ttl)
+	if err != nil {
+		return Meta{}, err
+	}
+
+	err = json.Unmarshal(res.body, response)
 	if err != nil {
-		return err
+		backend.Logger.Info("Unmarshal error", "err", string(err.Error()))
+
+		return Meta{}, err
 	}
 
-	req.Header.Add("Authorization", "Bearer "+c.AccessToken)
+	return res.meta(), nil
+}
 
-	res, err := c.client.Do(req)
+// response is a successful response, either fresh or from the cache.
+type response struct {
+	body     []byte
+	header   http.Header
+	cached   bool
+	storedAt time.Time
+}
+
+func (r response) meta() Meta {
+	meta := Meta{Pages: 1, Cached: r.cached}
+	if r.cached {
+		meta.CachedAt = r.storedAt
+	}
+
+	return meta
+}
+
+// get returns the response for url from the cache while it is younger than
+// ttl, and fetches it from Netlify otherwise. An expired response with an
+// ETag is revalidated with If-None-Match and reused when Netlify answers 304.
+// A zero ttl disables caching. Concurrent fetches of the same url share a
+// single upstream request.
+func (c Client) get(ctx context.Context, url string, ttl time.Duration) (response, error) {
+	key := cacheKey(url, c.AccessToken)
+	if ttl > 0 && !isNoCache(ctx) {
+		if entry, ok := c.cache.get(key); ok {
+			c.cache.record(cacheHit)
+			return response{body: entry.body, header: entry.header, cached: true, storedAt: entry.storedAt}, nil
+		}
+	}
+
+	body, header, shared, err := c.flights.do(ctx, key, func(ctx context.Context) ([]byte, http.Header, error) {
+		etag := ""
+		stale, hasStale := cacheEntry{}, false
+		if ttl > 0 {
+			stale, hasStale = c.cache.stale(key)
+			etag = stale.header.Get("ETag")
+		}
+
+		body, header, notModified, err := c.fetch(ctx, url, etag)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case notModified && hasStale:
+			c.cache.record(cacheNotModified)
+			body, header = stale.body, stale.header
+		case etag != "":
+			c.cache.record(cacheModified)
+		default:
+			c.cache.record(cacheMiss)
+		}
+
+		if ttl > 0 {
+			c.cache.set(key, body, header, ttl)
+		}
+
+		return body, header, nil
+	})
 	if err != nil {
-		return err
+		return response{}, err
+	}
+
+	if shared {
+		backend.Logger.Debug("shared in-flight request", "url", url)
 	}
-	defer res.Body.Close()
 
-	if res.StatusCode >= 400 {
-		defer res.Body.Close()
-		b, err := io.ReadAll(res.Body)
+	return response{body: body, header: header, storedAt: time.Now()}, nil
+}
+
+// CacheStats returns how requests were answered by the cache so far.
+func (c Client) CacheStats() CacheStats {
+	return c.cache.stats()
+}
+
+// ClearCache drops every cached response.
+func (c Client) ClearCache() {
+	c.cache.clear()
+}
+
+// fetch performs an authenticated GET and returns the body and headers of a
+// successful response. Requests wait while the rate limit budget is used up
+// and rate limited or failing upstream responses are retried with backoff.
+// With an etag the request is conditional and notModified reports a 304.
+func (c Client) fetch(ctx context.Context, url string, etag string) ([]byte, http.Header, bool, error) {
+	for attempt := 0; ; attempt++ {
+		if wait := c.rateLimit.delay(); wait > 0 {
+			backend.Logger.Debug("waiting for rate limit reset", "wait", wait)
+			if err := sleep(ctx, wait); err != nil {
+				return nil, nil, false, err
+			}
+		}
+
+		body, header, status, err := c.getOnce(ctx, url, etag)
 		if err != nil {
-			err = fmt.Errorf("error reading error body code: %d response: %s", res.StatusCode, err.Error())
-			return err
+			return nil, nil, false, err
+		}
+
+		if isRetryable(status) && attempt < c.MaxRetries {
+			wait := c.rateLimit.retryDelay(attempt, status, header)
+			backend.Logger.Debug("retrying request", "url", url, "status", status, "attempt", attempt+1, "wait", wait)
+			if err := sleep(ctx, wait); err != nil {
+				return nil, nil, false, err
+			}
+			continue
+		}
+
+		if status >= 400 {
+			return nil, nil, false, newAPIError(status, body)
 		}
 
-		err = fmt.Errorf("error: code: %d, response: %s", res.StatusCode, string(b))
-		return err
+		return body, header, status == http.StatusNotModified, nil
 	}
+}
 
-	body, err := io.ReadAll(res.Body)
+// getOnce sends a single request and records the rate limit state it reports.
+func (c Client) getOnce(ctx context.Context, url string, etag string) ([]byte, http.Header, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return err
+		return nil, nil, 0, err
 	}
 
-	err = json.Unmarshal(body, response)
+	if etag != "" {
+		req.Header.Add("If-None-Match", etag)
+	}
+
+	res, err := c.client.Do(req)
 	if err != nil {
-		backend.Logger.Info("Unmarshal error", "err", string(err.Error()))
+		if ctx.Err() != nil {
+			return nil, nil, 0, ctx.Err()
+		}
 
-		return err
+		return nil, nil, 0, fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
 	}
+	defer res.Body.Close()
+
+	c.rateLimit.update(res.Header)
 
-	return nil
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("error reading body code: %d response: %s", res.StatusCode, err.Error())
+	}
+
+	return body, res.Header, res.StatusCode, nil
 }
 
-type Doer[T any] func(s string) (T, error)
+// sleep waits for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
 
-func httpGetter[T any](ctx context.Context, doer Doer[T], variable string, ch chan T, error_ch chan error, wg *sync.WaitGroup) {
-	defer wg.Done()
-	backend.Logger.Info("httpGetter", "doing request")
-	res, err := doer(variable)
-	if err != nil {
-		backend.Logger.Info("httpGetter", "found error", "err", err.Error())
-		error_ch <- err
-		return
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RateLimit returns the rate limit state from the last Netlify response.
+func (c Client) RateLimit() RateLimit {
+	return c.rateLimit.State()
+}
+
+// Meta describes how a response was assembled from the Netlify API.
+type Meta struct {
+	// Pages is the number of pages fetched.
+	Pages int
+	// Truncated is set when more pages were available than MaxPages allowed.
+	Truncated bool
+	// Cached is set when every page was served from the cache.
+	Cached bool
+	// CachedAt is when the oldest cached page was fetched from Netlify.
+	CachedAt time.Time
+}
+
+// Merge combines the metadata of two responses that are merged into one result.
+func (m Meta) Merge(other Meta) Meta {
+	if m.Pages == 0 {
+		return other
+	}
+
+	if other.Pages == 0 {
+		return m
+	}
+
+	cachedAt := m.CachedAt
+	if cachedAt.IsZero() || (!other.CachedAt.IsZero() && other.CachedAt.Before(cachedAt)) {
+		cachedAt = other.CachedAt
+	}
+
+	return Meta{
+		Pages:     m.Pages + other.Pages,
+		Truncated: m.Truncated || other.Truncated,
+		Cached:    m.Cached && other.Cached,
+		CachedAt:  cachedAt,
 	}
+}
+
+type sinceKey struct{}
 
-	backend.Logger.Info("httpGetter", "got result", 0, "res", res)
-	ch <- res
+// WithSince returns a context whose list requests stop paginating once a page
+// only reaches back to items created before since. Netlify returns deploys,
+// builds and submissions newest first, so older pages cannot hold newer items.
+func WithSince(ctx context.Context, since time.Time) context.Context {
+	return context.WithValue(ctx, sinceKey{}, since)
 }
 
-func DoGets[T any](ctx context.Context, doer Doer[T], variables []string) ([]T, []error) {
-	backend.Logger.Info("DoGets", "variables", variables, "len", len(variables))
+func sinceFromContext(ctx context.Context) time.Time {
+	since, _ := ctx.Value(sinceKey{}).(time.Time)
+	return since
+}
+
+// getPages walks a paginated list endpoint page by page, following the next
+// link Netlify returns in the Link header, and appends every page into one
+// response. It stops after MaxPages pages and marks the result as truncated.
+// For endpoints sorted newest first createdAt is set, and walking stops at
+// the first page reaching past the time set with WithSince.
+func getPages[T ~[]E, E any](ctx context.Context, c Client, rawUrl string, ttl time.Duration, createdAt func(E) time.Time) (T, Meta, error) {
+	items := T{}
+	meta := Meta{}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return items, meta, err
+	}
 
-	var wg sync.WaitGroup
-	ch := make(chan T, len(variables))
-	error_ch := make(chan error, len(variables))
+	pageSize := c.PageSize
+	if pageSize <= 0 {
+		pageSize = models.DefaultPageSize
+	}
 
-	for _, variable := range variables {
-		wg.Add(1)
-		go httpGetter[T](ctx, doer, variable, ch, error_ch, &wg)
+	maxPages := c.MaxPages
+	if maxPages <= 0 {
+		maxPages = models.DefaultMaxPages
 	}
 
-	backend.Logger.Info("DoGets", "waiting for results")
-	go func() {
-		backend.Logger.Info("DoGets", "waiting on waitgroup")
-		wg.Wait()
-		backend.Logger.Info("DoGets", "done waiting closing channel")
-		close(ch)
-		close(error_ch)
-	}()
+	since := sinceFromContext(ctx)
+
+	page := 1
+	for {
+		params := u.Query()
+		params.Set("page", strconv.Itoa(page))
+		params.Set("per_page", strconv.Itoa(pageSize))
+		u.RawQuery = params.Encode()
+
+		res, err := c.get(ctx, u.String(), ttl)
+		if err != nil {
+			return items, meta, err
+		}
+
+		pageItems := T{}
+		err = json.Unmarshal(res.body, &pageItems)
+		if err != nil {
+			backend.Logger.Info("Unmarshal error", "err", string(err.Error()))
+
+			return items, meta, err
+		}
+
+		items = append(items, pageItems...)
+		meta = meta.Merge(res.meta())
 
-	backend.Logger.Info("DoGets", "receiving results")
+		next, ok := nextPage(res.header.Get("Link"))
+		if !ok || next <= page {
+			return items, meta, nil
+		}
+
+		if createdAt != nil && !since.IsZero() && len(pageItems) > 0 && createdAt(pageItems[len(pageItems)-1]).Before(since) {
+			return items, meta, nil
+		}
 
-	results := make([]T, 0, len(variables))
-	for r := range ch {
-		backend.Logger.Info("DoGets", "looping over results", "r", r, "len", len(results))
-		results = append(results, r)
+		if meta.Pages >= maxPages {
+			meta.Truncated = true
+			return items, meta, nil
+		}
+
+		page = next
 	}
+}
+
+// nextPage reads the page number of the rel="next" entry of a Link header like
+// <https://api.netlify.com/api/v1/sites?page=2&per_page=100>; rel="next".
+// Only the page number is used so the following request still goes through
+// the configured base url.
+func nextPage(link string) (int, bool) {
+	for _, part := range strings.Split(link, ",") {
+		segments := strings.Split(part, ";")
+		if len(segments) < 2 {
+			continue
+		}
+
+		isNext := false
+		for _, param := range segments[1:] {
+			if strings.ReplaceAll(strings.TrimSpace(param), " ", "") == `rel="next"` {
+				isNext = true
+			}
+		}
+
+		if !isNext {
+			continue
+		}
+
+		target := strings.Trim(strings.TrimSpace(segments[0]), "<>")
+		u, err := url.Parse(target)
+		if err != nil {
+			return 0, false
+		}
+
+		page, err := strconv.Atoi(u.Query().Get("page"))
+		if err != nil {
+			return 0, false
+		}
 
-	errors := make([]error, 0, len(variables))
-	for err := range error_ch {
-		errors = append(errors, err)
+		return page, true
 	}
 
-	backend.Logger.Info("DoGets", "returning results", "results", results, "errors", errors)
-	return results, errors
+	return 0, false
 }
 
-func (c Client) buildUrl(pattern string, siteId string) string {
-	sid := ""
-	if siteId != "" {
-		sid = siteId
-	} else {
-		sid = c.SiteId
+// buildUrl resolves an API path such as "/sites/{site_id}/deploys" against
+// the configured base url. The {site_id} placeholder falls back to the default
+// site from the settings and {account_id} is taken from the settings.
+func (c Client) buildUrl(pattern string, siteId string) (string, error) {
+	if siteId == "" {
+		siteId = c.SiteId
+	}
+
+	if strings.Contains(pattern, "{site_id}") && siteId == "" {
+		return "", fmt.Errorf("missing site id for %s", pattern)
+	}
+
+	if strings.Contains(pattern, "{account_id}") && c.AccountId == "" {
+		return "", fmt.Errorf("missing account id for %s", pattern)
+	}
+
+	pattern = strings.Replace(pattern, "{site_id}", url.PathEscape(siteId), -1)
+	pattern = strings.Replace(pattern, "{account_id}", url.PathEscape(c.AccountId), -1)
+
+	baseUrl := c.BaseUrl
+	if baseUrl == "" {
+		baseUrl = models.DefaultBaseUrl
 	}
 
-	pattern = strings.Replace(pattern, "{site_id}", sid, -1)
+	return url.JoinPath(baseUrl, pattern)
+}
 
-	return pattern
+// siteIdOr returns the site id reported by Netlify, or the site a row was
+// requested for so rows merged from several sites can still be told apart.
+func (c Client) siteIdOr(reported string, siteId string) string {
+	switch {
+	case reported != "":
+		return reported
+	case siteId != "":
+		return siteId
+	default:
+		return c.SiteId
+	}
 }
 
-type DeploysResponse []struct {
+type Deploy struct {
 	ID           string    `json:"id"`
+	SiteID       string    `json:"site_id"`
 	Build_id     string    `json:"build_id"`
 	State        string    `json:"state"` // ready, error, retrying
 	Name         string    `json:"name"`
@@ -157,23 +459,34 @@ type DeploysResponse []struct {
 	ManualDeploy bool      `json:"manual_deploy"`
 	ErrorMessage string    `json:"error_message"`
 	Branch       string    `json:"branch"`
+	CommitRef    string    `json:"commit_ref"`
+	Title        string    `json:"title"` // commit message
 	Context      string    `json:"context"`
 }
 
-func (c Client) GetDeployments(siteId string) (DeploysResponse, error) {
-	deploys := DeploysResponse{}
-	url := c.buildUrl("https://api.netlify.com/api/v1/sites/{site_id}/deploys", siteId)
+type DeploysResponse []Deploy
 
-	err := c.doGet(url, &deploys)
+// Created returns when the deploy was created.
+func (d Deploy) Created() time.Time { return d.CreatedAt }
+
+func (c Client) GetDeployments(ctx context.Context, siteId string) (DeploysResponse, Meta, error) {
+	deploys := DeploysResponse{}
+	url, err := c.buildUrl("/sites/{site_id}/deploys", siteId)
 	if err != nil {
-		return deploys, err
+		return deploys, Meta{}, err
 	}
 
-	return deploys, nil
+	deploys, meta, err := getPages[DeploysResponse](ctx, c, url, cacheTTL(c.CacheTTL.Deploys), Deploy.Created)
+	for i := range deploys {
+		deploys[i].SiteID = c.siteIdOr(deploys[i].SiteID, siteId)
+	}
+
+	return deploys, meta, err
 }
 
-type BuildsResponse []struct {
+type Build struct {
 	ID        string    `json:"id"`
+	SiteID    string    `json:"site_id"`
 	DeployID  string    `json:"deploy_id"`
 	Sha       string    `json:"sha"`
 	Done      bool      `json:"done"`
@@ -181,25 +494,33 @@ type BuildsResponse []struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type BuildsResponse []Build
+
+// Created returns when the build was created.
+func (b Build) Created() time.Time { return b.CreatedAt }
+
 type MapResponse = []map[string]any
 
 // state
 // "new" "pending_review" "accepted" "rejected" "enqueued" "building" "uploading" "uploaded" "preparing" "prepared" "processing" "processed" "ready" "error" "retrying"
-func (c Client) GetBuilds(siteId string) (BuildsResponse, error) {
+func (c Client) GetBuilds(ctx context.Context, siteId string) (BuildsResponse, Meta, error) {
 	backend.Logger.Info("GetBuilds", "siteId", siteId)
 
 	builds := BuildsResponse{}
-	url := c.buildUrl("https://api.netlify.com/api/v1/sites/{site_id}/builds", siteId)
-
-	err := c.doGet(url, &builds)
+	url, err := c.buildUrl("/sites/{site_id}/builds", siteId)
 	if err != nil {
-		return builds, err
+		return builds, Meta{}, err
+	}
+
+	builds, meta, err := getPages[BuildsResponse](ctx, c, url, cacheTTL(c.CacheTTL.Builds), Build.Created)
+	for i := range builds {
+		builds[i].SiteID = c.siteIdOr(builds[i].SiteID, siteId)
 	}
 
-	return builds, nil
+	return builds, meta, err
 }
 
-type SitesResponse []struct {
+type Site struct {
 	ID                        string    `json:"id"`
 	State                     string    `json:"state"`
 	Plan                      string    `json:"plan"`
@@ -301,18 +622,20 @@ type SitesResponse []struct {
 	FunctionsRegion string `json:"functions_region"`
 }
 
-func (c Client) GetSites() (SitesResponse, error) {
+type SitesResponse []Site
+
+func (c Client) GetSites(ctx context.Context) (SitesResponse, Meta, error) {
 	sites := SitesResponse{}
 
-	err := c.doGet("https://api.netlify.com/api/v1/sites", &sites)
+	url, err := c.buildUrl("/sites", "")
 	if err != nil {
-		return sites, err
+		return sites, Meta{}, err
 	}
 
-	return sites, nil
+	return getPages[SitesResponse](ctx, c, url, cacheTTL(c.CacheTTL.Sites), nil)
 }
 
-type FormsResponse []struct {
+type Form struct {
 	ID              string    `json:"id"`
 	SiteId          string    `json:"site_id"`
 	Name            string    `json:"name"`
@@ -321,20 +644,21 @@ type FormsResponse []struct {
 	CreatedAt       time.Time `json:"created_at"`
 }
 
-func (c Client) GetForms(siteId string) (FormsResponse, error) {
-	forms := FormsResponse{}
-	url := c.buildUrl("https://api.netlify.com/api/v1/sites/{site_id}/forms", siteId)
+type FormsResponse []Form
 
-	err := c.doGet(url, &forms)
+func (c Client) GetForms(ctx context.Context, siteId string) (FormsResponse, Meta, error) {
+	forms := FormsResponse{}
+	url, err := c.buildUrl("/sites/{site_id}/forms", siteId)
 	if err != nil {
-		return forms, err
+		return forms, Meta{}, err
 	}
 
-	return forms, nil
+	return getPages[FormsResponse](ctx, c, url, cacheTTL(c.CacheTTL.Forms), nil)
 }
 
-type FormSubmissionsResponse []struct {
+type FormSubmission struct {
 	Id        string            `json:"id"`
+	SiteID    string            `json:"site_id"`
 	Number    int64             `json:"number"`
 	Email     string            `json:"email"`
 	Name      string            `json:"name"`
@@ -348,16 +672,24 @@ type FormSubmissionsResponse []struct {
 	SiteUrl   string            `json:"site_url"`
 }
 
-func (c Client) GetFormSubmittions(siteId string) (FormSubmissionsResponse, error) {
-	submissions := FormSubmissionsResponse{}
-	url := c.buildUrl("https://api.netlify.com/api/v1/sites/{site_id}/submissions", siteId)
+type FormSubmissionsResponse []FormSubmission
 
-	err := c.doGet(url, &submissions)
+// Created returns when the submission was created.
+func (s FormSubmission) Created() time.Time { return s.CreatedAt }
+
+func (c Client) GetFormSubmittions(ctx context.Context, siteId string) (FormSubmissionsResponse, Meta, error) {
+	submissions := FormSubmissionsResponse{}
+	url, err := c.buildUrl("/sites/{site_id}/submissions", siteId)
 	if err != nil {
-		return submissions, err
+		return submissions, Meta{}, err
 	}
 
-	return submissions, nil
+	submissions, meta, err := getPages[FormSubmissionsResponse](ctx, c, url, cacheTTL(c.CacheTTL.Submissions), FormSubmission.Created)
+	for i := range submissions {
+		submissions[i].SiteID = c.siteIdOr(submissions[i].SiteID, siteId)
+	}
+
+	return submissions, meta, err
 }
 
 type BuildAccountResponse struct {
@@ -377,18 +709,23 @@ type BuildAccountResponse struct {
 	} `json:"minutes"`
 }
 
-func (c Client) GetBuildAccountDetails() (BuildAccountResponse, error) {
+func (c Client) GetBuildAccountDetails(ctx context.Context) (BuildAccountResponse, Meta, error) {
 	accountDetails := BuildAccountResponse{}
 
-	err := c.doGet("https://api.netlify.com/api/v1/"+c.AccountId+"/builds/status", &accountDetails)
+	url, err := c.buildUrl("/{account_id}/builds/status", "")
+	if err != nil {
+		return accountDetails, Meta{}, err
+	}
+
+	meta, err := c.doGet(ctx, url, cacheTTL(c.CacheTTL.BuildStatus), &accountDetails)
 	if err != nil {
-		return accountDetails, err
+		return accountDetails, meta, err
 	}
 
-	return accountDetails, nil
+	return accountDetails, meta, nil
 }
 
-type AccountResponse []struct {
+type Account struct {
 	ID           string `json:"id"`
 	Name         string `json:"name"`
 	Slug         string `json:"slug"`
@@ -416,13 +753,20 @@ type AccountResponse []struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-func (c Client) GetAccounts() (AccountResponse, error) {
+type AccountResponse []Account
+
+func (c Client) GetAccounts(ctx context.Context) (AccountResponse, Meta, error) {
 	accountDetails := AccountResponse{}
 
-	err := c.doGet("https://api.netlify.com/api/v1/accounts", &accountDetails)
+	url, err := c.buildUrl("/accounts", "")
+	if err != nil {
+		return accountDetails, Meta{}, err
+	}
+
+	meta, err := c.doGet(ctx, url, cacheTTL(c.CacheTTL.Accounts), &accountDetails)
 	if err != nil {
-		return accountDetails, err
+		return accountDetails, meta, err
 	}
 
-	return accountDetails, nil
+	return accountDetails, meta, nil
 }
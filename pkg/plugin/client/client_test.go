@@ -0,0 +1,306 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+func newTestClient(t *testing.T, settings models.Settings) Client {
+	t.Helper()
+
+	c, err := NewClient(settings, httpclient.Options{})
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestNewClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer my-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "platform", r.Header.Get("X-Team"))
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	c, err := NewClient(models.Settings{BaseUrl: server.URL, AccessToken: "my-token", RequestTimeout: 5}, httpclient.Options{
+		Headers: map[string]string{"X-Team": "platform"},
+	})
+	require.NoError(t, err)
+
+	_, _, err = c.GetAccounts(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, c.client.Timeout)
+
+	t.Run("keeps the timeout of the http settings", func(t *testing.T) {
+		c, err := NewClient(models.Settings{BaseUrl: server.URL}, httpclient.Options{
+			Timeouts: &httpclient.TimeoutOptions{Timeout: 7 * time.Second},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 7*time.Second, c.client.Timeout)
+	})
+}
+
+func TestBuildUrl(t *testing.T) {
+	t.Run("joins paths onto the configured base url", func(t *testing.T) {
+		c := newTestClient(t, models.Settings{BaseUrl: "http://localhost:8080/proxy/api/v1", SiteId: "default-site"})
+
+		url, err := c.buildUrl("/sites/{site_id}/deploys", "my-site")
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080/proxy/api/v1/sites/my-site/deploys", url)
+
+		url, err = c.buildUrl("/sites/{site_id}/builds", "")
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080/proxy/api/v1/sites/default-site/builds", url)
+	})
+
+	t.Run("falls back to the default base url", func(t *testing.T) {
+		c := newTestClient(t, models.Settings{AccountId: "my-account"})
+
+		url, err := c.buildUrl("/{account_id}/builds/status", "")
+		require.NoError(t, err)
+		assert.Equal(t, "https://api.netlify.com/api/v1/my-account/builds/status", url)
+	})
+
+	t.Run("escapes path parameters", func(t *testing.T) {
+		c := newTestClient(t, models.Settings{})
+
+		url, err := c.buildUrl("/sites/{site_id}/forms", "../accounts")
+		require.NoError(t, err)
+		assert.Equal(t, "https://api.netlify.com/api/v1/sites/..%2Faccounts/forms", url)
+	})
+
+	t.Run("returns error when a placeholder cannot be filled", func(t *testing.T) {
+		c := newTestClient(t, models.Settings{})
+
+		_, err := c.buildUrl("/sites/{site_id}/deploys", "")
+		assert.Error(t, err)
+
+		_, err = c.buildUrl("/{account_id}/builds/status", "")
+		assert.Error(t, err)
+	})
+}
+
+// newPagedServer serves /sites/my-site/deploys as totalPages pages of one
+// deploy each, linking to the next page like the Netlify API does.
+func newPagedServer(t *testing.T, totalPages int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/sites/my-site/deploys", r.URL.Path)
+		assert.Equal(t, "Bearer my-token", r.Header.Get("Authorization"))
+
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		require.NoError(t, err)
+
+		if page < totalPages {
+			w.Header().Set("Link", fmt.Sprintf(`<https://api.netlify.com/api/v1/sites/my-site/deploys?page=%d&per_page=1>; rel="next", <https://api.netlify.com/api/v1/sites/my-site/deploys?page=%d&per_page=1>; rel="last"`, page+1, totalPages))
+		}
+
+		// newest first like Netlify, one day older on every page
+		fmt.Fprintf(w, `[{"id":"deploy-%d","created_at":"2024-01-%02dT00:00:00Z"}]`, page, 20-page)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGetPages(t *testing.T) {
+	t.Run("walks every page", func(t *testing.T) {
+		server := newPagedServer(t, 3)
+		c := newTestClient(t, models.Settings{BaseUrl: server.URL + "/api/v1", AccessToken: "my-token", PageSize: 1})
+
+		deploys, meta, err := c.GetDeployments(context.Background(), "my-site")
+		require.NoError(t, err)
+		require.Len(t, deploys, 3)
+		assert.Equal(t, "deploy-1", deploys[0].ID)
+		assert.Equal(t, "deploy-3", deploys[2].ID)
+		assert.Equal(t, Meta{Pages: 3}, meta)
+	})
+
+	t.Run("stops at max pages", func(t *testing.T) {
+		server := newPagedServer(t, 5)
+		c := newTestClient(t, models.Settings{BaseUrl: server.URL + "/api/v1", AccessToken: "my-token", PageSize: 1, MaxPages: 2})
+
+		deploys, meta, err := c.GetDeployments(context.Background(), "my-site")
+		require.NoError(t, err)
+		assert.Len(t, deploys, 2)
+		assert.Equal(t, Meta{Pages: 2, Truncated: true}, meta)
+	})
+
+	t.Run("stops once items are older than since", func(t *testing.T) {
+		server := newPagedServer(t, 5)
+		c := newTestClient(t, models.Settings{BaseUrl: server.URL + "/api/v1", AccessToken: "my-token", PageSize: 1})
+
+		ctx := WithSince(context.Background(), time.Date(2024, 1, 18, 12, 0, 0, 0, time.UTC))
+		deploys, meta, err := c.GetDeployments(ctx, "my-site")
+		require.NoError(t, err)
+		require.Len(t, deploys, 2)
+		assert.Equal(t, "deploy-2", deploys[1].ID)
+		assert.Equal(t, Meta{Pages: 2}, meta)
+	})
+}
+
+func TestNextPage(t *testing.T) {
+	page, ok := nextPage(`<https://api.netlify.com/api/v1/sites?page=1&per_page=100>; rel="prev", <https://api.netlify.com/api/v1/sites?page=3&per_page=100>; rel="next"`)
+	assert.True(t, ok)
+	assert.Equal(t, 3, page)
+
+	_, ok = nextPage(`<https://api.netlify.com/api/v1/sites?page=1&per_page=100>; rel="first"`)
+	assert.False(t, ok)
+
+	_, ok = nextPage("")
+	assert.False(t, ok)
+}
+
+func TestRetries(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+
+	t.Run("retries rate limited and failing responses", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch calls.Add(1) {
+			case 1:
+				w.WriteHeader(http.StatusTooManyRequests)
+			case 2:
+				w.WriteHeader(http.StatusBadGateway)
+			default:
+				w.Header().Set("X-RateLimit-Limit", "500")
+				w.Header().Set("X-RateLimit-Remaining", "499")
+				w.Header().Set("X-RateLimit-Reset", "1700000000")
+				fmt.Fprint(w, `[{"id":"my-account"}]`)
+			}
+		}))
+		defer server.Close()
+
+		c := newTestClient(t, models.Settings{BaseUrl: server.URL, MaxRetries: 3})
+
+		accounts, _, err := c.GetAccounts(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, accounts, 1)
+		assert.Equal(t, int32(3), calls.Load())
+
+		rateLimit := c.RateLimit()
+		assert.Equal(t, 500, rateLimit.Limit)
+		assert.Equal(t, 499, rateLimit.Remaining)
+		assert.Equal(t, time.Unix(1700000000, 0), rateLimit.Reset)
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		c := newTestClient(t, models.Settings{BaseUrl: server.URL, MaxRetries: 2})
+
+		_, _, err := c.GetAccounts(context.Background())
+		assert.Error(t, err)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		c := newTestClient(t, models.Settings{BaseUrl: server.URL, MaxRetries: 2})
+
+		_, _, err := c.GetAccounts(context.Background())
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+}
+
+func TestContextCancellation(t *testing.T) {
+	t.Run("aborts in-flight requests", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer server.Close()
+
+		c := newTestClient(t, models.Settings{BaseUrl: server.URL})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, _, err := c.GetAccounts(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("does not start requests once cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var calls atomic.Int32
+		doer := func(ctx context.Context, siteId string) (DeploysResponse, Meta, error) {
+			calls.Add(1)
+			return DeploysResponse{}, Meta{}, nil
+		}
+
+		results := DoGets[DeploysResponse](ctx, doer, []string{"a", "b", "c"}, 2)
+		assert.Empty(t, Flatten(results))
+		assert.Len(t, results.Errors(), 3)
+		assert.Equal(t, int32(0), calls.Load())
+	})
+}
+
+func TestDoGets(t *testing.T) {
+	t.Run("keeps results in variable order and bounds concurrency", func(t *testing.T) {
+		var running, maxRunning atomic.Int32
+		doer := func(ctx context.Context, siteId string) (DeploysResponse, Meta, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+
+			// finish the first variables last to make ordering observable
+			time.Sleep(time.Duration(10-len(siteId)) * time.Millisecond)
+			if siteId == "err" {
+				return nil, Meta{}, fmt.Errorf("failed %s", siteId)
+			}
+
+			return DeploysResponse{{ID: siteId}}, Meta{Pages: 1}, nil
+		}
+
+		variables := []string{"a", "bb", "err", "dddd", "eeeee", "ffffff"}
+		results := DoGets[DeploysResponse](context.Background(), doer, variables, 2)
+
+		require.Len(t, results, len(variables))
+		for i, result := range results {
+			assert.Equal(t, variables[i], result.Variable)
+		}
+
+		assert.LessOrEqual(t, maxRunning.Load(), int32(2))
+		assert.EqualError(t, results[2].Err, "failed err")
+		assert.Len(t, results.Errors(), 1)
+		assert.Equal(t, Meta{Pages: 5}, results.Meta())
+
+		deploys := Flatten(results)
+		ids := make([]string, len(deploys))
+		for i, deploy := range deploys {
+			ids[i] = deploy.ID
+		}
+		assert.Equal(t, []string{"a", "bb", "dddd", "eeeee", "ffffff"}, ids)
+	})
+}
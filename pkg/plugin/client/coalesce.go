@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// flight is an upstream request shared by every caller asking for the same
+// key while it is in progress.
+type flight struct {
+	done    chan struct{}
+	body    []byte
+	header  http.Header
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// flightGroup deduplicates identical concurrent requests, in the spirit of
+// golang.org/x/sync/singleflight. The shared request is detached from the
+// context of the caller that started it and is only cancelled once every
+// waiting caller has given up.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{flights: map[string]*flight{}}
+}
+
+// do runs fn once for all concurrent callers of key and reports whether the
+// result was shared with another caller.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, http.Header, error)) ([]byte, http.Header, bool, error) {
+	if g == nil {
+		body, header, err := fn(ctx)
+		return body, header, false, err
+	}
+
+	g.mu.Lock()
+	f, shared := g.flights[key]
+	if !shared {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.flights[key] = f
+
+		go func() {
+			defer cancel()
+
+			f.body, f.header, f.err = fn(flightCtx)
+
+			g.mu.Lock()
+			g.forget(key, f)
+			g.mu.Unlock()
+
+			close(f.done)
+		}()
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.body, f.header, shared, f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			g.forget(key, f)
+		}
+		g.mu.Unlock()
+
+		return nil, nil, shared, ctx.Err()
+	}
+}
+
+// forget removes f unless a newer flight already took its place, g.mu must
+// be held.
+func (g *flightGroup) forget(key string, f *flight) {
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+// waitForWaiters blocks until n callers wait on the in-flight request for key.
+func waitForWaiters(t *testing.T, g *flightGroup, key string, n int) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		f, ok := g.flights[key]
+		return ok && f.waiters == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestRequestCoalescing(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		fmt.Fprint(w, `[{"id":"my-site"}]`)
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, models.Settings{BaseUrl: server.URL, AccessToken: "my-token"})
+	url, err := c.buildUrl("/sites", "")
+	require.NoError(t, err)
+
+	t.Run("identical requests share one upstream call", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sites, _, err := c.GetSites(context.Background())
+				assert.NoError(t, err)
+				assert.Len(t, sites, 1)
+			}()
+		}
+
+		waitForWaiters(t, c.flights, cacheKey(url+"?page=1&per_page=100", "my-token"), 5)
+		release <- struct{}{}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("a cancelled caller does not fail the others", func(t *testing.T) {
+		calls.Store(0)
+		ctx, cancel := context.WithCancel(context.Background())
+		key := cacheKey(url+"?page=1&per_page=100", "my-token")
+
+		errs := make(chan error, 2)
+		go func() {
+			_, _, err := c.GetSites(ctx)
+			errs <- err
+		}()
+		waitForWaiters(t, c.flights, key, 1)
+
+		go func() {
+			_, _, err := c.GetSites(context.Background())
+			errs <- err
+		}()
+		waitForWaiters(t, c.flights, key, 2)
+
+		cancel()
+		assert.ErrorIs(t, <-errs, context.Canceled)
+
+		release <- struct{}{}
+		assert.NoError(t, <-errs)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Kinds of upstream failures an APIError can be matched against with
+// errors.Is.
+var (
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	ErrNotFound            = errors.New("not found")
+	ErrRateLimited         = errors.New("rate limited")
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+)
+
+type ErrorResponse struct {
+	Code    int64  `json:"code"`
+	Message string `json:"message"`
+}
+
+// APIError is an error response returned by the Netlify API.
+type APIError struct {
+	StatusCode int
+	Message    string
+	kind       error
+}
+
+// newAPIError parses a Netlify error body like {"code":401,"message":"Access Denied"}
+// and falls back to the raw body when it is not JSON.
+func newAPIError(statusCode int, body []byte) *APIError {
+	message := strings.TrimSpace(string(body))
+
+	errorResponse := ErrorResponse{}
+	if err := json.Unmarshal(body, &errorResponse); err == nil && errorResponse.Message != "" {
+		message = errorResponse.Message
+	}
+
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+
+	return &APIError{
+		StatusCode: statusCode,
+		Message:    message,
+		kind:       errorKind(statusCode),
+	}
+}
+
+func errorKind(statusCode int) error {
+	switch {
+	case statusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case statusCode == http.StatusForbidden:
+		return ErrForbidden
+	case statusCode == http.StatusNotFound:
+		return ErrNotFound
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode >= http.StatusInternalServerError:
+		return ErrUpstreamUnavailable
+	default:
+		return nil
+	}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("netlify api error: code: %d, message: %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
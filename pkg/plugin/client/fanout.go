@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+type Doer[T any] func(ctx context.Context, s string) (T, Meta, error)
+
+// Result is the outcome of a single Doer call, keyed by the variable that
+// produced it.
+type Result[T any] struct {
+	Variable string
+	Value    T
+	Meta     Meta
+	Err      error
+}
+
+// Results holds one Result per variable, in the order the variables were
+// passed to DoGets.
+type Results[T any] []Result[T]
+
+// Errors returns the errors of the failed results.
+func (r Results[T]) Errors() []error {
+	errors := make([]error, 0)
+	for _, result := range r {
+		if result.Err != nil {
+			errors = append(errors, result.Err)
+		}
+	}
+
+	return errors
+}
+
+// Meta merges the metadata of the successful results.
+func (r Results[T]) Meta() Meta {
+	meta := Meta{}
+	for _, result := range r {
+		if result.Err == nil {
+			meta = meta.Merge(result.Meta)
+		}
+	}
+
+	return meta
+}
+
+// Flatten concatenates the values of the successful results, keeping the
+// order of the variables.
+func Flatten[T ~[]E, E any](results Results[T]) T {
+	items := T{}
+	for _, result := range results {
+		if result.Err == nil {
+			items = append(items, result.Value...)
+		}
+	}
+
+	return items
+}
+
+// DoGets calls doer once per variable using a pool of at most concurrency
+// workers. Results are returned in the order of variables. Once ctx is done no
+// new calls are started and the remaining variables report ctx.Err().
+func DoGets[T any](ctx context.Context, doer Doer[T], variables []string, concurrency int) Results[T] {
+	backend.Logger.Debug("DoGets", "variables", variables, "concurrency", concurrency)
+
+	results := make(Results[T], len(variables))
+	for i, variable := range variables {
+		results[i].Variable = variable
+	}
+
+	if concurrency <= 0 {
+		concurrency = models.DefaultConcurrency
+	}
+
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, len(variables)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := &results[i]
+				if err := ctx.Err(); err != nil {
+					result.Err = err
+					continue
+				}
+
+				result.Value, result.Meta, result.Err = doer(ctx, result.Variable)
+			}
+		}()
+	}
+
+	next := 0
+feed:
+	for ; next < len(variables); next++ {
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for ; next < len(variables); next++ {
+		results[next].Err = ctx.Err()
+	}
+
+	return results
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// retryBaseDelay is the first backoff step between retries, it doubles on
+	// every following attempt up to retryMaxDelay.
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+	// maxRateLimitWait bounds how long a request waits for the rate limit
+	// window to reset, Netlify windows are one minute long.
+	maxRateLimitWait = time.Minute
+)
+
+// RateLimit is the latest rate limit state reported by Netlify through the
+// X-RateLimit-* response headers.
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// rateLimiter keeps track of the remaining request budget shared by every
+// request made through a client.
+type rateLimiter struct {
+	mu    sync.Mutex
+	state RateLimit
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{}
+}
+
+// update records the rate limit headers of a response. Responses without the
+// headers, e.g. from a local stand-in, leave the state untouched.
+func (r *rateLimiter) update(header http.Header) {
+	if r == nil {
+		return
+	}
+
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	state := RateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		UpdatedAt: time.Now(),
+	}
+
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		state.Reset = time.Unix(reset, 0)
+	}
+
+	r.mu.Lock()
+	r.state = state
+	r.mu.Unlock()
+}
+
+// State returns the last known rate limit state.
+func (r *rateLimiter) State() RateLimit {
+	if r == nil {
+		return RateLimit{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.state
+}
+
+// delay returns how long to wait before the next request can be sent: until
+// the window resets when the budget is used up, otherwise zero.
+func (r *rateLimiter) delay() time.Duration {
+	state := r.State()
+	if state.Limit == 0 || state.Remaining > 0 || state.Reset.IsZero() {
+		return 0
+	}
+
+	return min(time.Until(state.Reset), maxRateLimitWait)
+}
+
+// isRetryable reports whether a response status is worth retrying.
+func isRetryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// retryDelay picks the wait before retrying a failed attempt. Rate limited
+// responses wait for the reported reset or Retry-After when available, every
+// other case uses exponential backoff with full jitter.
+func (r *rateLimiter) retryDelay(attempt int, status int, header http.Header) time.Duration {
+	if status == http.StatusTooManyRequests {
+		if wait := r.delay(); wait > 0 {
+			return wait
+		}
+
+		if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+			return min(time.Duration(seconds)*time.Second, maxRateLimitWait)
+		}
+	}
+
+	backoff := retryMaxDelay
+	if attempt < 16 {
+		backoff = min(retryBaseDelay<<attempt, retryMaxDelay)
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
@@ -2,15 +2,23 @@ package plugin
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 
 	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/events"
+	"github.com/grafana/netlify-datasource/pkg/plugin/history"
 	"github.com/grafana/netlify-datasource/pkg/plugin/models"
 	"github.com/grafana/netlify-datasource/pkg/plugin/query"
 	"github.com/grafana/netlify-datasource/pkg/plugin/resources"
+	"github.com/grafana/netlify-datasource/pkg/plugin/sampler"
+	"github.com/grafana/netlify-datasource/pkg/plugin/stream"
 )
 
 // Datasource is an example datasource which can respond to data queries, reports
@@ -18,6 +26,9 @@ import (
 type Datasource struct {
 	client       client.Client
 	queryHandler query.QueryHandler
+	streams      *stream.Hub
+	samples      *sampler.Sampler
+	history      *history.Store
 	backend.CallResourceHandler
 }
 
@@ -31,6 +42,7 @@ type Datasource struct {
 var (
 	_ backend.QueryDataHandler      = (*Datasource)(nil)
 	_ backend.CheckHealthHandler    = (*Datasource)(nil)
+	_ backend.StreamHandler         = (*Datasource)(nil)
 	_ instancemgmt.InstanceDisposer = (*Datasource)(nil)
 	// _ backend.CallResourceHandler   = (*resources.ResourcesHandler.Router)(nil)
 )
@@ -42,25 +54,80 @@ func NewDatasource(ctx context.Context, config backend.DataSourceInstanceSetting
 		return nil, err
 	}
 
-	client := client.NewClient(settings)
-	query := query.NewQueryHandler(client)
+	httpOptions, err := config.HTTPClientOptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get http client options: %w", err)
+	}
+
+	client, err := client.NewClient(settings, httpOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	streams := stream.NewHub(client.GetDeployments, client.RateLimit, time.Duration(settings.StreamInterval)*time.Second)
+	events := events.NewStore(events.DefaultMaxPerSite)
+
+	// history is optional, another process still holding the database must
+	// not take the data source down
+	var store *history.Store
+	if settings.EnableHistory {
+		store, err = openHistory(config.UID)
+		if err != nil {
+			backend.Logger.Warn("failed to open history, continuing without it", "err", err.Error())
+			store = nil
+		}
+	}
+
+	// the build status belongs to the account, there is nothing to sample
+	// without one
+	var samples *sampler.Sampler
+	if settings.SampleInterval > 0 && settings.AccountId != "" {
+		samples = sampler.Start(client.GetBuildAccountDetails, time.Duration(settings.SampleInterval)*time.Second, settings.SampleSize)
+	}
 
-	resourcesHandler := resources.NewResourcesHandler(client)
+	query := query.NewQueryHandler(client, events, store, samples)
+
+	resourcesHandler := resources.NewResourcesHandler(client, streams, events)
 
 	ds := Datasource{
 		client:              client,
 		queryHandler:        query,
+		streams:             streams,
+		samples:             samples,
+		history:             store,
 		CallResourceHandler: httpadapter.New(resourcesHandler.Router),
 	}
 
 	return &ds, nil
 }
 
+// openHistory opens the history of a data source in the plugin data
+// directory, settings never choose where the plugin writes.
+func openHistory(uid string) (*history.Store, error) {
+	dataDir, err := history.DataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := history.DatasourceDir(dataDir, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	return history.Open(dir)
+}
+
 // Dispose here tells plugin SDK that plugin wants to clean up resources when a new instance
 // created. As soon as datasource settings change detected by SDK old datasource instance will
 // be disposed and a new one will be created using NewSampleDatasource factory function.
 func (d *Datasource) Dispose() {
 	// Clean up datasource instance resources.
+	d.streams.Close()
+	d.samples.Close()
+	if err := d.history.Close(); err != nil {
+		backend.Logger.Warn("failed to close history", "err", err.Error())
+	}
+	d.client.ClearCache()
 }
 
 // QueryData handles multiple queries and returns multiple responses.
@@ -75,23 +142,96 @@ func (d *Datasource) QueryData(ctx context.Context, req *backend.QueryDataReques
 // The main use case for these health checks is the test button on the
 // datasource configuration page which allows users to verify that
 // a datasource is working as expected.
-func (d *Datasource) CheckHealth(_ context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+func (d *Datasource) CheckHealth(ctx context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
 	var status = backend.HealthStatusOk
 	var message = "Data source is working"
 
 	// TODO:
 	// validate settings on health check
 
-	_, err := d.client.GetSites()
+	// always reach out to Netlify, a cached response would hide a revoked token
+	_, _, err := d.client.GetSites(client.NoCache(ctx))
 	if err != nil {
 		return &backend.CheckHealthResult{
-			Status:  backend.HealthStatusError,
-			Message: err.Error(),
+			Status:      backend.HealthStatusError,
+			Message:     err.Error(),
+			JSONDetails: d.healthDetails(),
 		}, nil
 	}
 
 	return &backend.CheckHealthResult{
-		Status:  status,
-		Message: message,
+		Status:      status,
+		Message:     message,
+		JSONDetails: d.healthDetails(),
 	}, nil
 }
+
+// healthDetails reports the current Netlify rate limit budget and cache
+// counters alongside the health check result.
+func (d *Datasource) healthDetails() []byte {
+	details, err := json.Marshal(map[string]any{
+		"rateLimit": d.client.RateLimit(),
+		"cache":     d.client.CacheStats(),
+	})
+	if err != nil {
+		backend.Logger.Warn("failed to marshal health details", "err", err.Error())
+		return nil
+	}
+
+	return details
+}
+
+// SubscribeStream allows subscribing to the deploys of a site on the
+// deploys/<siteId> path, or deploys/<siteId>/<optionsKey> for the options of
+// a query registered by running it.
+func (d *Datasource) SubscribeStream(ctx context.Context, req *backend.SubscribeStreamRequest) (*backend.SubscribeStreamResponse, error) {
+	_, optionsKey, ok := stream.ParseDeploysPath(req.Path)
+	if !ok {
+		return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusNotFound}, nil
+	}
+
+	if _, known := stream.Options(optionsKey); optionsKey != "" && !known {
+		return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusNotFound}, nil
+	}
+
+	return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusOK}, nil
+}
+
+// PublishStream rejects publications, deploy streams are fed by Netlify only.
+func (d *Datasource) PublishStream(ctx context.Context, req *backend.PublishStreamRequest) (*backend.PublishStreamResponse, error) {
+	return &backend.PublishStreamResponse{Status: backend.PublishStreamStatusPermissionDenied}, nil
+}
+
+// RunStream sends the deploys of a site that changed until Grafana stops
+// the stream, filtered and projected like the query of the channel. Every
+// stream of a site shares the same poller.
+func (d *Datasource) RunStream(ctx context.Context, req *backend.RunStreamRequest, sender *backend.StreamSender) error {
+	siteId, optionsKey, ok := stream.ParseDeploysPath(req.Path)
+	if !ok {
+		return fmt.Errorf("unknown stream path %q", req.Path)
+	}
+
+	updates, unsubscribe := d.streams.Subscribe(siteId)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case deploys := <-updates:
+			frame, err := query.StreamFrame(optionsKey, deploys, d.client.Settings)
+			if err != nil {
+				return err
+			}
+
+			// none of the changed deploys pass the filters of the query
+			if frame == nil {
+				continue
+			}
+
+			if err := sender.SendFrame(frame, data.IncludeAll); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -2,6 +2,8 @@ package plugin
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -26,3 +28,28 @@ func TestQueryData(t *testing.T) {
 		t.Fatal("QueryData must return a response")
 	}
 }
+
+func TestNewDatasourceWithoutHistory(t *testing.T) {
+	// a file where the data directory should be fails opening the history
+	dataPath := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(dataPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GF_PATHS_DATA", dataPath)
+
+	instance, err := NewDatasource(context.Background(), backend.DataSourceInstanceSettings{
+		UID:                     "abc",
+		JSONData:                []byte(`{"enableHistory":true,"sampleInterval":-1}`),
+		DecryptedSecureJSONData: map[string]string{"accessToken": "token"},
+	})
+	if err != nil {
+		t.Fatalf("history failures must not fail the data source: %v", err)
+	}
+
+	ds := instance.(*Datasource)
+	defer ds.Dispose()
+
+	if ds.history != nil {
+		t.Error("history must be disabled when it cannot be opened")
+	}
+}
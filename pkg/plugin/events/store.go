@@ -0,0 +1,62 @@
+package events
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+// DefaultMaxPerSite is how many deploy events are kept per site.
+const DefaultMaxPerSite = 1000
+
+// Store keeps the deploy events Netlify notified through webhooks in memory,
+// so annotations show deploys before the API lists them. The latest state of
+// every deploy is kept, up to a bounded number of deploys per site.
+type Store struct {
+	mu         sync.Mutex
+	maxPerSite int
+	deploys    map[string]client.DeploysResponse
+}
+
+func NewStore(maxPerSite int) *Store {
+	return &Store{
+		maxPerSite: maxPerSite,
+		deploys:    map[string]client.DeploysResponse{},
+	}
+}
+
+// Add records the latest state of a deploy of a site.
+func (s *Store) Add(siteId string, deploy client.Deploy) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deploys := slices.DeleteFunc(s.deploys[siteId], func(d client.Deploy) bool { return d.ID == deploy.ID })
+	deploys = append(deploys, deploy)
+	if len(deploys) > s.maxPerSite {
+		deploys = slices.Delete(deploys, 0, len(deploys)-s.maxPerSite)
+	}
+
+	s.deploys[siteId] = deploys
+}
+
+// Deploys returns the deploys recorded for the sites, oldest event first.
+func (s *Store) Deploys(siteIds ...string) client.DeploysResponse {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deploys := client.DeploysResponse{}
+	for _, siteId := range siteIds {
+		deploys = append(deploys, s.deploys[siteId]...)
+	}
+
+	return deploys
+}
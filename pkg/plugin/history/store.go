@@ -0,0 +1,273 @@
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+// pluginId names the data directory of the plugin.
+const pluginId = "grafana-netlify-datasource"
+
+// DataDir returns the directory the plugin keeps its data in, below the data
+// path of Grafana, or below the user cache directory when Grafana does not
+// pass its data path on.
+func DataDir() (string, error) {
+	if dataPath := os.Getenv("GF_PATHS_DATA"); dataPath != "" {
+		return filepath.Join(dataPath, "plugins-data", pluginId), nil
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the plugin data directory: %w", err)
+	}
+
+	return filepath.Join(cacheDir, pluginId), nil
+}
+
+// DatasourceDir returns the history directory of a data source below the
+// plugin data directory.
+func DatasourceDir(dataDir string, uid string) (string, error) {
+	name := url.PathEscape(uid)
+	if name == "" || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid data source uid %q", uid)
+	}
+
+	return filepath.Join(dataDir, "history", name), nil
+}
+
+// Store keeps the deploys and builds of every site in an embedded database,
+// so history that Netlify no longer lists stays queryable. Rows are keyed by
+// site, creation time and id, so they are deduplicated by id and read by
+// time range.
+type Store struct {
+	db   *database
+	once sync.Once
+}
+
+// database is shared by every store of a directory, the old and the new
+// instance of a data source write the same rows while its settings change.
+type database struct {
+	dir  string
+	db   *bolt.DB
+	refs int
+}
+
+var (
+	openMu sync.Mutex
+	opened = map[string]*database{}
+)
+
+// Open returns a store writing below dir, creating it when missing. Close
+// the store once done.
+func Open(dir string) (*Store, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid history directory: %w", err)
+	}
+
+	openMu.Lock()
+	defer openMu.Unlock()
+
+	if d, ok := opened[dir]; ok {
+		d.refs++
+		return &Store{db: d}, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	// the file lock keeps other processes out, waiting would hang the
+	// data source
+	db, err := bolt.Open(filepath.Join(dir, "history.db"), 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+
+	d := &database{dir: dir, db: db, refs: 1}
+	opened[dir] = d
+
+	return &Store{db: d}, nil
+}
+
+// Close releases the store, the database closes with its last store.
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+
+	var err error
+	s.once.Do(func() {
+		openMu.Lock()
+		defer openMu.Unlock()
+
+		s.db.refs--
+		if s.db.refs == 0 {
+			delete(opened, s.db.dir)
+			err = s.db.db.Close()
+		}
+	})
+
+	return err
+}
+
+// Deploys returns the stored deploys of a site created from from until
+// before to, newest first. A zero from or to leaves that side open.
+func (s *Store) Deploys(siteId string, from time.Time, to time.Time) (client.DeploysResponse, error) {
+	return load[client.DeploysResponse](s, "deploys", siteId, from, to)
+}
+
+// AddDeploys stores the deploys of a site, replacing earlier states of the
+// same deploys.
+func (s *Store) AddDeploys(siteId string, deploys client.DeploysResponse) error {
+	return add(s, "deploys", siteId, deploys, func(d client.Deploy) string { return d.ID }, client.Deploy.Created)
+}
+
+// Builds returns the stored builds of a site created from from until before
+// to, newest first. A zero from or to leaves that side open.
+func (s *Store) Builds(siteId string, from time.Time, to time.Time) (client.BuildsResponse, error) {
+	return load[client.BuildsResponse](s, "builds", siteId, from, to)
+}
+
+// AddBuilds stores the builds of a site, replacing earlier states of the
+// same builds.
+func (s *Store) AddBuilds(siteId string, builds client.BuildsResponse) error {
+	return add(s, "builds", siteId, builds, func(b client.Build) string { return b.ID }, client.Build.Created)
+}
+
+// key orders the rows of a site by creation time, the id keeps rows created
+// at the same time apart.
+func key(created time.Time, id string) []byte {
+	k := binary.BigEndian.AppendUint64(nil, uint64(created.UnixNano()))
+	return append(k, id...)
+}
+
+// siteBucket returns the bucket of the rows of a site, nil when the site has
+// no rows yet and the transaction is read only.
+func siteBucket(tx *bolt.Tx, entity string, siteId string) (*bolt.Bucket, error) {
+	if siteId == "" {
+		return nil, errors.New("missing site id")
+	}
+
+	if !tx.Writable() {
+		entities := tx.Bucket([]byte(entity))
+		if entities == nil {
+			return nil, nil
+		}
+
+		return entities.Bucket([]byte(siteId)), nil
+	}
+
+	entities, err := tx.CreateBucketIfNotExists([]byte(entity))
+	if err != nil {
+		return nil, err
+	}
+
+	return entities.CreateBucketIfNotExists([]byte(siteId))
+}
+
+func load[T ~[]E, E any](s *Store, entity string, siteId string, from time.Time, to time.Time) (T, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	var rows T
+	err := s.db.db.View(func(tx *bolt.Tx) error {
+		bucket, err := siteBucket(tx, entity, siteId)
+		if err != nil || bucket == nil {
+			return err
+		}
+
+		c := bucket.Cursor()
+		k, v := c.Last()
+		if !to.IsZero() {
+			// the last row created before to
+			k, v = c.Seek(key(to, ""))
+			if k == nil {
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		}
+
+		var first []byte
+		if !from.IsZero() {
+			first = key(from, "")
+		}
+
+		for ; k != nil && bytes.Compare(k, first) >= 0; k, v = c.Prev() {
+			var row E
+			if err := json.Unmarshal(v, &row); err != nil {
+				return fmt.Errorf("failed to parse history of %s: %w", siteId, err)
+			}
+
+			rows = append(rows, row)
+		}
+
+		return nil
+	})
+
+	return rows, err
+}
+
+// add writes the rows that are new or changed, most queries return rows
+// that are already stored and only read.
+func add[T ~[]E, E any](s *Store, entity string, siteId string, rows T, id func(E) string, createdAt func(E) time.Time) error {
+	if s == nil || len(rows) == 0 {
+		return nil
+	}
+
+	type entry struct{ key, value []byte }
+
+	changed := []entry{}
+	err := s.db.db.View(func(tx *bolt.Tx) error {
+		bucket, err := siteBucket(tx, entity, siteId)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			value, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+
+			k := key(createdAt(row), id(row))
+			if bucket == nil || !bytes.Equal(bucket.Get(k), value) {
+				changed = append(changed, entry{k, value})
+			}
+		}
+
+		return nil
+	})
+	if err != nil || len(changed) == 0 {
+		return err
+	}
+
+	return s.db.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := siteBucket(tx, entity, siteId)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range changed {
+			if err := bucket.Put(e.key, e.value); err != nil {
+				return fmt.Errorf("failed to write history of %s: %w", siteId, err)
+			}
+		}
+
+		return nil
+	})
+}
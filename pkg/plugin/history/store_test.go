@@ -0,0 +1,143 @@
+package history
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+func TestStore(t *testing.T) {
+	at := func(day int) time.Time { return time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC) }
+	ids := func(deploys client.DeploysResponse) []string {
+		ids := []string{}
+		for _, deploy := range deploys {
+			ids = append(ids, deploy.ID)
+		}
+		return ids
+	}
+
+	open := func(t *testing.T, dir string) *Store {
+		store, err := Open(dir)
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+		return store
+	}
+
+	t.Run("deduplicates rows newest first", func(t *testing.T) {
+		store := open(t, t.TempDir())
+
+		require.NoError(t, store.AddDeploys("site-a", client.DeploysResponse{
+			{ID: "deploy-2", State: "building", CreatedAt: at(2)},
+			{ID: "deploy-1", State: "ready", CreatedAt: at(1)},
+		}))
+		require.NoError(t, store.AddDeploys("site-a", client.DeploysResponse{
+			{ID: "deploy-3", State: "ready", CreatedAt: at(3)},
+			{ID: "deploy-2", State: "ready", CreatedAt: at(2)},
+		}))
+
+		deploys, err := store.Deploys("site-a", time.Time{}, time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"deploy-3", "deploy-2", "deploy-1"}, ids(deploys))
+		assert.Equal(t, "ready", deploys[1].State)
+	})
+
+	t.Run("reads time ranges", func(t *testing.T) {
+		store := open(t, t.TempDir())
+		require.NoError(t, store.AddDeploys("site-a", client.DeploysResponse{
+			{ID: "deploy-4", CreatedAt: at(4)},
+			{ID: "deploy-3", CreatedAt: at(3)},
+			{ID: "deploy-2", CreatedAt: at(2)},
+			{ID: "deploy-1", CreatedAt: at(1)},
+		}))
+
+		deploys, err := store.Deploys("site-a", at(2), at(4))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"deploy-3", "deploy-2"}, ids(deploys))
+
+		deploys, err = store.Deploys("site-a", time.Time{}, at(2))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"deploy-1"}, ids(deploys))
+
+		deploys, err = store.Deploys("site-a", at(5), time.Time{})
+		require.NoError(t, err)
+		assert.Empty(t, deploys)
+	})
+
+	t.Run("keeps sites and entities apart", func(t *testing.T) {
+		store := open(t, t.TempDir())
+
+		require.NoError(t, store.AddDeploys("site-a", client.DeploysResponse{{ID: "deploy-1", CreatedAt: at(1)}}))
+		require.NoError(t, store.AddBuilds("site-a", client.BuildsResponse{{ID: "build-1", CreatedAt: at(1)}}))
+
+		deploys, err := store.Deploys("site-b", time.Time{}, time.Time{})
+		require.NoError(t, err)
+		assert.Empty(t, deploys)
+
+		builds, err := store.Builds("site-a", time.Time{}, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, builds, 1)
+		assert.Equal(t, "build-1", builds[0].ID)
+	})
+
+	t.Run("shares the database of a directory", func(t *testing.T) {
+		dir := t.TempDir()
+		old, current := open(t, dir), open(t, dir)
+
+		// the old instance of a data source still writes while the new one
+		// takes over
+		var wg sync.WaitGroup
+		for i, store := range []*Store{old, current} {
+			wg.Add(1)
+			go func(i int, store *Store) {
+				defer wg.Done()
+				assert.NoError(t, store.AddDeploys("site-a", client.DeploysResponse{{ID: []string{"deploy-1", "deploy-2"}[i], CreatedAt: at(i + 1)}}))
+			}(i, store)
+		}
+		wg.Wait()
+
+		require.NoError(t, old.Close())
+		deploys, err := current.Deploys("site-a", time.Time{}, time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"deploy-2", "deploy-1"}, ids(deploys))
+	})
+
+	t.Run("persists across reopening", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := Open(dir)
+		require.NoError(t, err)
+		require.NoError(t, store.AddDeploys("site-a", client.DeploysResponse{{ID: "deploy-1", CreatedAt: at(1)}}))
+		require.NoError(t, store.Close())
+
+		deploys, err := open(t, dir).Deploys("site-a", time.Time{}, time.Time{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"deploy-1"}, ids(deploys))
+	})
+
+	t.Run("nil store is empty", func(t *testing.T) {
+		var store *Store
+		require.NoError(t, store.AddDeploys("site-a", client.DeploysResponse{{ID: "deploy-1"}}))
+		deploys, err := store.Deploys("site-a", time.Time{}, time.Time{})
+		require.NoError(t, err)
+		assert.Empty(t, deploys)
+		assert.NoError(t, store.Close())
+	})
+}
+
+func TestDatasourceDir(t *testing.T) {
+	dir, err := DatasourceDir("/data", "abc-123")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/data", "history", "abc-123"), dir)
+
+	dir, err = DatasourceDir("/data", "../../etc")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/data", "history", "..%2F..%2Fetc"), dir)
+
+	_, err = DatasourceDir("/data", "..")
+	assert.Error(t, err)
+}
@@ -4,19 +4,117 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 )
 
+// DefaultBaseUrl is the Netlify API root used when no baseUrl is configured.
+const DefaultBaseUrl = "https://api.netlify.com/api/v1"
+
+const (
+	// DefaultPageSize is the per_page value sent to paginated endpoints, it is
+	// also the largest page Netlify serves.
+	DefaultPageSize = 100
+	// DefaultMaxPages caps how many pages a single list request walks.
+	DefaultMaxPages = 10
+	// DefaultMaxRetries is how often rate limited or failing requests are
+	// retried, set maxRetries to 0 to disable retries.
+	DefaultMaxRetries = 3
+	// DefaultQueryTimeout is the timeout in seconds of a whole query, covering
+	// every page, retry and site it fans out to.
+	DefaultQueryTimeout = 120
+	// DefaultConcurrency is how many sites a multi-site query requests at once.
+	DefaultConcurrency = 4
+	// DefaultCacheSize is the number of responses kept in the cache.
+	DefaultCacheSize = 500
+	// DefaultStreamInterval is how many seconds live deploy streams wait
+	// between polls of a site.
+	DefaultStreamInterval = 10
+	// DefaultSampleInterval is how many seconds pass between samples of the
+	// build status, set sampleInterval to a negative value to disable
+	// sampling.
+	DefaultSampleInterval = 60
+	// DefaultSampleSize is the number of build status samples kept, a week
+	// at the default interval.
+	DefaultSampleSize = 10080
+)
+
+// CacheTTL holds how many seconds responses of each entity are cached. Zero
+// uses the default and a negative value disables caching for the entity.
+type CacheTTL struct {
+	Sites       int `json:"sites"`
+	Deploys     int `json:"deploys"`
+	Builds      int `json:"builds"`
+	Forms       int `json:"forms"`
+	Submissions int `json:"submissions"`
+	Accounts    int `json:"accounts"`
+	BuildStatus int `json:"buildStatus"`
+}
+
+// DefaultCacheTTL keeps rarely changing entities like sites and accounts for
+// minutes, and builds and deploys only for a few seconds.
+var DefaultCacheTTL = CacheTTL{
+	Sites:       300,
+	Deploys:     30,
+	Builds:      15,
+	Forms:       300,
+	Submissions: 60,
+	Accounts:    3600,
+	BuildStatus: 15,
+}
+
+// withDefaults replaces every unset TTL with its default.
+func (c CacheTTL) withDefaults() CacheTTL {
+	ttls := []*int{&c.Sites, &c.Deploys, &c.Builds, &c.Forms, &c.Submissions, &c.Accounts, &c.BuildStatus}
+	defaults := []int{DefaultCacheTTL.Sites, DefaultCacheTTL.Deploys, DefaultCacheTTL.Builds, DefaultCacheTTL.Forms, DefaultCacheTTL.Submissions, DefaultCacheTTL.Accounts, DefaultCacheTTL.BuildStatus}
+
+	for i, ttl := range ttls {
+		if *ttl == 0 {
+			*ttl = defaults[i]
+		}
+	}
+
+	return c
+}
+
 type Settings struct {
 	AccessToken string `json:"accessToken"`
 	SiteId      string `json:"siteId"`
 	AccountId   string `json:"accountId"`
 	BaseUrl     string `json:"baseUrl"`
+	PageSize    int    `json:"pageSize"`
+	MaxPages    int    `json:"maxPages"`
+	MaxRetries  int    `json:"maxRetries"`
+	// RequestTimeout and QueryTimeout are in seconds, RequestTimeout overrides
+	// the timeout of the standard HTTP settings when set.
+	RequestTimeout int      `json:"requestTimeout"`
+	QueryTimeout   int      `json:"queryTimeout"`
+	Concurrency    int      `json:"concurrency"`
+	CacheTTL       CacheTTL `json:"cacheTTL"`
+	CacheSize      int      `json:"cacheSize"`
+	// StreamInterval is in seconds.
+	StreamInterval int `json:"streamInterval"`
+	// WebhookSecret signs the Netlify webhook notifications, webhooks are
+	// rejected while it is empty.
+	WebhookSecret string `json:"-"`
+	// EnableHistory keeps the deploy and build history in the plugin data
+	// directory.
+	EnableHistory bool `json:"enableHistory"`
+	// SampleInterval is in seconds.
+	SampleInterval int `json:"sampleInterval"`
+	SampleSize     int `json:"sampleSize"`
+	// RedactFields are redacted from the rows of every entity that has them,
+	// on top of the built-in sensitive fields such as site passwords.
+	RedactFields []string `json:"redactFields"`
+	// DisableRedaction shows every field including the sensitive ones. Like
+	// every setting it can only be changed by admins of the data source.
+	DisableRedaction bool `json:"disableRedaction"`
 }
 
 func LoadSettings(ctx context.Context, config backend.DataSourceInstanceSettings) (Settings, error) {
-	s := Settings{}
+	s := Settings{MaxRetries: DefaultMaxRetries}
 	if err := json.Unmarshal(config.JSONData, &s); err != nil {
 		return Settings{}, fmt.Errorf("failed to unmarshal settings JSONData: %w", err)
 	}
@@ -27,6 +125,86 @@ func LoadSettings(ctx context.Context, config backend.DataSourceInstanceSettings
 	}
 
 	s.AccessToken = accessToken
+	s.WebhookSecret = config.DecryptedSecureJSONData["webhookSecret"]
+
+	baseUrl, err := normalizeBaseUrl(s.BaseUrl)
+	if err != nil {
+		return Settings{}, err
+	}
+
+	s.BaseUrl = baseUrl
+
+	if s.PageSize <= 0 || s.PageSize > DefaultPageSize {
+		s.PageSize = DefaultPageSize
+	}
+
+	if s.MaxPages <= 0 {
+		s.MaxPages = DefaultMaxPages
+	}
+
+	if s.MaxRetries < 0 {
+		s.MaxRetries = 0
+	}
+
+	// without a request timeout the timeout of the HTTP settings applies
+	if s.RequestTimeout < 0 {
+		s.RequestTimeout = 0
+	}
+
+	if s.QueryTimeout <= 0 {
+		s.QueryTimeout = DefaultQueryTimeout
+	}
+
+	if s.Concurrency <= 0 {
+		s.Concurrency = DefaultConcurrency
+	}
+
+	s.CacheTTL = s.CacheTTL.withDefaults()
+
+	if s.CacheSize <= 0 {
+		s.CacheSize = DefaultCacheSize
+	}
+
+	if s.StreamInterval <= 0 {
+		s.StreamInterval = DefaultStreamInterval
+	}
+
+	if s.SampleInterval == 0 {
+		s.SampleInterval = DefaultSampleInterval
+	}
+
+	if s.SampleSize <= 0 {
+		s.SampleSize = DefaultSampleSize
+	}
 
 	return s, nil
 }
+
+// normalizeBaseUrl falls back to DefaultBaseUrl when baseUrl is empty and
+// makes sure a configured value is an absolute http(s) URL that paths can be
+// joined onto.
+func normalizeBaseUrl(baseUrl string) (string, error) {
+	baseUrl = strings.TrimSpace(baseUrl)
+	if baseUrl == "" {
+		return DefaultBaseUrl, nil
+	}
+
+	u, err := url.Parse(baseUrl)
+	if err != nil {
+		return "", fmt.Errorf("invalid baseUrl %q: %w", baseUrl, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid baseUrl %q: scheme must be http or https", baseUrl)
+	}
+
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid baseUrl %q: missing host", baseUrl)
+	}
+
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid baseUrl %q: query and fragment are not allowed", baseUrl)
+	}
+
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
@@ -37,4 +37,83 @@ func TestLoadSettings(t *testing.T) {
 		_, err := LoadSettings(context.Background(), config)
 		assert.Error(t, err, "accessToken is missing")
 	})
+	t.Run("defaults baseUrl to the Netlify API", func(t *testing.T) {
+		t.Parallel()
+
+		config := backend.DataSourceInstanceSettings{
+			JSONData: []byte(`{"siteId":"my-site-id"}`),
+			DecryptedSecureJSONData: map[string]string{
+				"accessToken": "my-access-token",
+			},
+		}
+
+		settings, err := LoadSettings(context.Background(), config)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultBaseUrl, settings.BaseUrl)
+		assert.Equal(t, DefaultPageSize, settings.PageSize)
+		assert.Equal(t, DefaultMaxPages, settings.MaxPages)
+		assert.Equal(t, DefaultMaxRetries, settings.MaxRetries)
+	})
+
+	t.Run("allows disabling retries", func(t *testing.T) {
+		t.Parallel()
+
+		config := backend.DataSourceInstanceSettings{
+			JSONData: []byte(`{"maxRetries":0}`),
+			DecryptedSecureJSONData: map[string]string{
+				"accessToken": "my-access-token",
+			},
+		}
+
+		settings, err := LoadSettings(context.Background(), config)
+		require.NoError(t, err)
+		assert.Equal(t, 0, settings.MaxRetries)
+	})
+
+	t.Run("keeps configured pagination", func(t *testing.T) {
+		t.Parallel()
+
+		config := backend.DataSourceInstanceSettings{
+			JSONData: []byte(`{"pageSize":50, "maxPages":3}`),
+			DecryptedSecureJSONData: map[string]string{
+				"accessToken": "my-access-token",
+			},
+		}
+
+		settings, err := LoadSettings(context.Background(), config)
+		require.NoError(t, err)
+		assert.Equal(t, 50, settings.PageSize)
+		assert.Equal(t, 3, settings.MaxPages)
+	})
+
+	t.Run("trims trailing slash from baseUrl", func(t *testing.T) {
+		t.Parallel()
+
+		config := backend.DataSourceInstanceSettings{
+			JSONData: []byte(`{"baseUrl":"http://localhost:3000/api/v1/"}`),
+			DecryptedSecureJSONData: map[string]string{
+				"accessToken": "my-access-token",
+			},
+		}
+
+		settings, err := LoadSettings(context.Background(), config)
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:3000/api/v1", settings.BaseUrl)
+	})
+
+	t.Run("returns error when baseUrl is invalid", func(t *testing.T) {
+		t.Parallel()
+
+		for _, baseUrl := range []string{"localhost:3000", "ftp://localhost", "http://", "http://localhost?a=b"} {
+			config := backend.DataSourceInstanceSettings{
+				JSONData: []byte(`{"baseUrl":"` + baseUrl + `"}`),
+				DecryptedSecureJSONData: map[string]string{
+					"accessToken": "my-access-token",
+				},
+			}
+
+			_, err := LoadSettings(context.Background(), config)
+			assert.Error(t, err, baseUrl)
+		}
+	})
 }
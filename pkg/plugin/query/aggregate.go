@@ -0,0 +1,230 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	// aggregateFormatMulti returns a frame per group, the default.
+	aggregateFormatMulti = "multi"
+	// aggregateFormatLong returns a single frame with a group column.
+	aggregateFormatLong = "long"
+)
+
+// defaultInterval buckets queries that do not carry an interval.
+const defaultInterval = time.Hour
+
+// maxBuckets caps the buckets of a time series, wider intervals are used for
+// longer time ranges.
+const maxBuckets = 10000
+
+// aggregation turns the rows of a query into the number of rows created per
+// interval, e.g. {"groupBy":"state"} for deploys per hour by state.
+type aggregation struct {
+	// GroupBy splits the counts by the values of a field, "site" is a
+	// shorthand for SiteID.
+	GroupBy string `json:"groupBy"`
+	// Format is "multi" or "long", see aggregateFormatMulti and
+	// aggregateFormatLong.
+	Format string `json:"format"`
+
+	groupBy *fieldRef
+}
+
+// aggregatedEntities are the entities whose rows carry a creation time.
+var aggregatedEntities = []string{"builds", "deployments", "form-submissions"}
+
+// compileAggregation validates the aggregation of a query against the
+// schema of its entity.
+func compileAggregation(entity string, agg *aggregation) error {
+	if agg == nil {
+		return nil
+	}
+
+	if !slices.Contains(aggregatedEntities, entity) {
+		return fmt.Errorf("entity %q cannot be aggregated, expected one of: %v", entity, aggregatedEntities)
+	}
+
+	if agg.Format == "" {
+		agg.Format = aggregateFormatMulti
+	}
+
+	if agg.Format != aggregateFormatMulti && agg.Format != aggregateFormatLong {
+		return fmt.Errorf("unknown format %q, expected %q or %q", agg.Format, aggregateFormatMulti, aggregateFormatLong)
+	}
+
+	if agg.GroupBy == "" {
+		return nil
+	}
+
+	name := agg.GroupBy
+	if name == "site" {
+		name = "SiteID"
+	}
+
+	field, err := entitySchemas[entity].field(entity, name)
+	if err != nil {
+		return err
+	}
+
+	agg.groupBy = &field
+	return nil
+}
+
+// aggregate counts the rows created in every interval of the time range per
+// group. Intervals without rows are counted as zero so the series have no
+// gaps.
+func aggregate[T ~[]E, E any](name string, rows T, agg *aggregation, timeRange backend.TimeRange, interval time.Duration, createdAt func(E) time.Time) data.Frames {
+	from, to := timeRange.From, timeRange.To
+	if from.IsZero() || to.IsZero() {
+		from, to = rowsTimeRange(rows, createdAt)
+	}
+
+	interval = bucketInterval(from, to, interval, 0)
+	from = from.Truncate(interval)
+	buckets := timeBuckets(from, to, interval)
+
+	groups := []string{}
+	counts := map[string][]int64{}
+	for _, row := range rows {
+		created := createdAt(row)
+		if created.Before(from) || created.After(to) {
+			continue
+		}
+
+		group := ""
+		if agg.groupBy != nil {
+			group = formatValue(agg.groupBy.value(reflect.ValueOf(row)))
+		}
+
+		if _, ok := counts[group]; !ok {
+			groups = append(groups, group)
+			counts[group] = make([]int64, len(buckets))
+		}
+
+		counts[group][created.Sub(from)/interval]++
+	}
+
+	slices.Sort(groups)
+
+	if len(groups) == 0 {
+		// keep an unlabeled zero series so empty time ranges still chart and
+		// alert
+		groups = []string{""}
+		counts[""] = make([]int64, len(buckets))
+		agg = &aggregation{Format: agg.Format}
+	}
+
+	if agg.Format == aggregateFormatLong {
+		return data.Frames{longFrame(name, buckets, groups, counts, agg)}
+	}
+
+	return multiFrames(name, buckets, groups, counts, agg)
+}
+
+func multiFrames(name string, buckets []time.Time, groups []string, counts map[string][]int64, agg *aggregation) data.Frames {
+	frames := make(data.Frames, 0, len(groups))
+	for _, group := range groups {
+		var labels data.Labels
+		if agg.groupBy != nil {
+			labels = data.Labels{agg.groupBy.name(): group}
+		}
+
+		frame := data.NewFrame(name,
+			data.NewField("time", nil, buckets),
+			data.NewField("count", labels, counts[group]),
+		)
+		frame.Meta = &data.FrameMeta{Type: data.FrameTypeTimeSeriesMulti}
+		frames = append(frames, frame)
+	}
+
+	return frames
+}
+
+func longFrame(name string, buckets []time.Time, groups []string, counts map[string][]int64, agg *aggregation) *data.Frame {
+	times := []time.Time{}
+	values := []int64{}
+	groupValues := []string{}
+	for i, bucket := range buckets {
+		for _, group := range groups {
+			times = append(times, bucket)
+			groupValues = append(groupValues, group)
+			values = append(values, counts[group][i])
+		}
+	}
+
+	frame := data.NewFrame(name, data.NewField("time", nil, times))
+	if agg.groupBy != nil {
+		frame.Fields = append(frame.Fields, data.NewField(agg.groupBy.name(), nil, groupValues))
+	}
+	frame.Fields = append(frame.Fields, data.NewField("count", nil, values))
+	frame.Meta = &data.FrameMeta{Type: data.FrameTypeTimeSeriesLong}
+
+	return frame
+}
+
+// rowsTimeRange returns the time range spanned by the rows, for queries
+// without a time range.
+func rowsTimeRange[T ~[]E, E any](rows T, createdAt func(E) time.Time) (time.Time, time.Time) {
+	var from, to time.Time
+	for _, row := range rows {
+		created := createdAt(row)
+		if from.IsZero() || created.Before(from) {
+			from = created
+		}
+
+		if created.After(to) {
+			to = created
+		}
+	}
+
+	return from, to
+}
+
+// bucketInterval widens interval so the time range holds at most
+// maxDataPoints, when set, and maxBuckets buckets. Alerting queries for
+// instance come with a one second interval whatever their time range.
+func bucketInterval(from time.Time, to time.Time, interval time.Duration, maxDataPoints int64) time.Duration {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	span := to.Sub(from)
+	if span <= 0 {
+		return interval
+	}
+
+	for _, limit := range []int64{maxDataPoints, maxBuckets} {
+		if limit <= 0 {
+			continue
+		}
+
+		// round up so the buckets never exceed the limit
+		if least := (span + time.Duration(limit) - 1) / time.Duration(limit); least > interval {
+			interval = least
+		}
+	}
+
+	return interval
+}
+
+// timeBuckets returns the start of every interval from from, which must be
+// truncated to the interval, up to to.
+func timeBuckets(from time.Time, to time.Time, interval time.Duration) []time.Time {
+	buckets := []time.Time{}
+	if from.IsZero() {
+		return buckets
+	}
+
+	for t := from; !t.After(to); t = t.Add(interval) {
+		buckets = append(buckets, t)
+	}
+
+	return buckets
+}
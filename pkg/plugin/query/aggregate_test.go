@@ -0,0 +1,113 @@
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+func TestAggregate(t *testing.T) {
+	at := func(hour, minute int) time.Time { return time.Date(2024, 1, 1, hour, minute, 0, 0, time.UTC) }
+	deploys := client.DeploysResponse{
+		{ID: "deploy-1", State: "ready", SiteID: "site-a", CreatedAt: at(10, 5)},
+		{ID: "deploy-2", State: "error", SiteID: "site-b", CreatedAt: at(10, 45)},
+		{ID: "deploy-3", State: "ready", SiteID: "site-a", CreatedAt: at(12, 30)},
+	}
+	timeRange := backend.TimeRange{From: at(10, 0), To: at(12, 59)}
+
+	t.Run("multi frames per group", func(t *testing.T) {
+		agg := &aggregation{GroupBy: "state"}
+		require.NoError(t, compileAggregation("deployments", agg))
+
+		frames := aggregate("deployments", deploys, agg, timeRange, time.Hour, client.Deploy.Created)
+		require.Len(t, frames, 2)
+
+		assert.Equal(t, data.FrameTypeTimeSeriesMulti, frames[0].Meta.Type)
+		assert.Equal(t, data.Labels{"State": "error"}, frames[0].Fields[1].Labels)
+		assert.Equal(t, []int64{1, 0, 0}, fieldValues[int64](frames[0].Fields[1]))
+		assert.Equal(t, data.Labels{"State": "ready"}, frames[1].Fields[1].Labels)
+		assert.Equal(t, []int64{1, 0, 1}, fieldValues[int64](frames[1].Fields[1]))
+		assert.Equal(t, []time.Time{at(10, 0), at(11, 0), at(12, 0)}, fieldValues[time.Time](frames[1].Fields[0]))
+	})
+
+	t.Run("long frame grouped by site", func(t *testing.T) {
+		agg := &aggregation{GroupBy: "site", Format: aggregateFormatLong}
+		require.NoError(t, compileAggregation("deployments", agg))
+
+		frames := aggregate("deployments", deploys, agg, timeRange, time.Hour, client.Deploy.Created)
+		require.Len(t, frames, 1)
+
+		frame := frames[0]
+		assert.Equal(t, data.FrameTypeTimeSeriesLong, frame.Meta.Type)
+		assert.Equal(t, []string{"site-a", "site-b", "site-a", "site-b", "site-a", "site-b"}, fieldValues[string](frame.Fields[1]))
+		assert.Equal(t, []int64{1, 1, 0, 0, 1, 0}, fieldValues[int64](frame.Fields[2]))
+	})
+
+	t.Run("zero series without rows", func(t *testing.T) {
+		agg := &aggregation{GroupBy: "state"}
+		require.NoError(t, compileAggregation("deployments", agg))
+
+		frames := aggregate("deployments", client.DeploysResponse{}, agg, timeRange, time.Hour, client.Deploy.Created)
+		require.Len(t, frames, 1)
+		assert.Nil(t, frames[0].Fields[1].Labels)
+		assert.Equal(t, []int64{0, 0, 0}, fieldValues[int64](frames[0].Fields[1]))
+	})
+
+	t.Run("groups submissions by site", func(t *testing.T) {
+		agg := &aggregation{GroupBy: "site"}
+		require.NoError(t, compileAggregation("form-submissions", agg))
+
+		submissions := client.FormSubmissionsResponse{{Id: "submission-1", SiteID: "site-a", CreatedAt: at(10, 5)}}
+		frames := aggregate("form_submissions", submissions, agg, timeRange, time.Hour, client.FormSubmission.Created)
+		require.Len(t, frames, 1)
+		assert.Equal(t, data.Labels{"SiteID": "site-a"}, frames[0].Fields[1].Labels)
+	})
+
+	t.Run("caps the buckets of long time ranges", func(t *testing.T) {
+		month := backend.TimeRange{From: at(0, 0), To: at(0, 0).AddDate(0, 0, 30)}
+
+		frames := aggregate("deployments", deploys, &aggregation{Format: aggregateFormatMulti}, month, time.Second, client.Deploy.Created)
+		require.Len(t, frames, 1)
+		assert.LessOrEqual(t, frames[0].Rows(), maxBuckets+1)
+	})
+
+	t.Run("rejects offset and limit", func(t *testing.T) {
+		handler := NewQueryHandler(newTestClient(t, models.Settings{}), nil, nil, nil)
+		res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON: []byte(`{"entity":"deployments","aggregate":{"groupBy":"state"},"limit":10}`),
+		})
+		assert.Equal(t, backend.StatusBadRequest, res.Status)
+		assert.Contains(t, res.Error.Error(), "offset and limit cannot be combined with aggregate")
+	})
+
+	t.Run("rejects entities without a creation time", func(t *testing.T) {
+		assert.ErrorContains(t, compileAggregation("sites", &aggregation{}), `entity "sites" cannot be aggregated`)
+		assert.ErrorContains(t, compileAggregation("deployments", &aggregation{Format: "wide"}), `unknown format "wide"`)
+	})
+}
+
+func fieldValues[V any](field *data.Field) []V {
+	values := make([]V, field.Len())
+	for i := range values {
+		values[i] = field.At(i).(V)
+	}
+	return values
+}
+
+func TestBucketInterval(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	assert.Equal(t, defaultInterval, bucketInterval(from, to, 0, 0))
+	assert.Equal(t, time.Minute, bucketInterval(from, to, time.Minute, 0))
+	assert.Equal(t, 24*time.Minute, bucketInterval(from, to, time.Second, 60))
+	assert.Equal(t, 2*time.Hour, bucketInterval(from, from.Add(2*maxBuckets*time.Hour), time.Second, 0))
+}
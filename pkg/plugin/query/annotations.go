@@ -0,0 +1,149 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+// HandleDeployAnnotationsQuery returns the deploys of every site as
+// annotation events, from their creation until they went live. Deploys
+// notified through webhooks show up before the API lists them. Filters and
+// order apply to the deploy fields, e.g. Context == production.
+func (q QueryHandler) HandleDeployAnnotationsQuery(ctx context.Context, qm queryModel, siteIds []string) backend.DataResponse {
+	var response backend.DataResponse
+
+	ctx = client.WithSince(ctx, qm.TimeRange.From)
+	results := client.DoGets[client.DeploysResponse](ctx, q.deploysDoer(qm), siteIds, q.client.Concurrency)
+	notices, err := siteNotices(results, qm.ErrorMode)
+	if err != nil {
+		return errorResponse(err, "failed to get deployments")
+	}
+
+	notified := q.events.Deploys(q.resolveSiteIds(siteIds)...)
+	deployments := inTimeRange(mergeDeploys(client.Flatten(results), notified), qm.TimeRange, client.Deploy.Created)
+	deployments = shapeRows(deployments, qm)
+	meta := results.Meta()
+
+	frame := deployAnnotations(deployments)
+	applyMeta(frame, meta)
+	frame.AppendNotices(notices...)
+
+	response.Frames = append(response.Frames, frame)
+
+	return response
+}
+
+// resolveSiteIds replaces the empty site id with the configured default.
+func (q QueryHandler) resolveSiteIds(siteIds []string) []string {
+	resolved := make([]string, len(siteIds))
+	for i, siteId := range siteIds {
+		resolved[i] = q.resolveSiteId(siteId)
+	}
+
+	return resolved
+}
+
+func (q QueryHandler) resolveSiteId(siteId string) string {
+	if siteId == "" {
+		return q.client.SiteId
+	}
+
+	return siteId
+}
+
+// mergeDeploys adds the notified deploys to the listed ones, keeping the most
+// recently updated state of deploys found in both.
+func mergeDeploys(listed client.DeploysResponse, notified client.DeploysResponse) client.DeploysResponse {
+	if len(notified) == 0 {
+		return listed
+	}
+
+	merged := slices.Clone(listed)
+	index := make(map[string]int, len(merged))
+	for i, deploy := range merged {
+		index[deploy.ID] = i
+	}
+
+	for _, deploy := range notified {
+		i, ok := index[deploy.ID]
+		switch {
+		case !ok:
+			index[deploy.ID] = len(merged)
+			merged = append(merged, deploy)
+		case deploy.UpdatedAt.After(merged[i].UpdatedAt):
+			merged[i] = deploy
+		}
+	}
+
+	return merged
+}
+
+// deployAnnotations converts deploys into the time, timeEnd, title, text
+// and tags fields Grafana reads annotations from.
+func deployAnnotations(deploys client.DeploysResponse) *data.Frame {
+	times := make([]time.Time, 0, len(deploys))
+	timeEnds := make([]time.Time, 0, len(deploys))
+	titles := make([]string, 0, len(deploys))
+	texts := make([]string, 0, len(deploys))
+	tags := make([]string, 0, len(deploys))
+
+	for _, deploy := range deploys {
+		end := deploy.PublishedAt
+		if end.IsZero() || end.Before(deploy.CreatedAt) {
+			end = deploy.UpdatedAt
+		}
+		if end.Before(deploy.CreatedAt) {
+			end = deploy.CreatedAt
+		}
+
+		site := deploy.Name
+		if site == "" {
+			site = deploy.SiteID
+		}
+
+		text := []string{}
+		if deploy.Branch != "" {
+			text = append(text, "Branch: "+deploy.Branch)
+		}
+		if deploy.CommitRef != "" {
+			text = append(text, strings.TrimSpace("Commit: "+deploy.CommitRef+" "+deploy.Title))
+		}
+		if deploy.ErrorMessage != "" {
+			text = append(text, "Error: "+deploy.ErrorMessage)
+		}
+
+		times = append(times, deploy.CreatedAt)
+		timeEnds = append(timeEnds, end)
+		titles = append(titles, fmt.Sprintf("Deploy %s: %s", deploy.State, site))
+		texts = append(texts, strings.Join(text, "\n"))
+		tags = append(tags, strings.Join(nonEmpty(deploy.State, deploy.Context), ","))
+	}
+
+	return data.NewFrame("annotations",
+		data.NewField("time", nil, times),
+		data.NewField("timeEnd", nil, timeEnds),
+		data.NewField("title", nil, titles),
+		data.NewField("text", nil, texts),
+		// Grafana splits the tags on commas
+		data.NewField("tags", nil, tags),
+	)
+}
+
+func nonEmpty(values ...string) []string {
+	kept := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" {
+			kept = append(kept, value)
+		}
+	}
+
+	return kept
+}
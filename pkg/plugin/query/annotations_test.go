@@ -0,0 +1,42 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+func TestDeployAnnotations(t *testing.T) {
+	created := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	frame := deployAnnotations(client.DeploysResponse{
+		{Name: "my-site", State: "ready", Context: "production", Branch: "main", CommitRef: "abc123", Title: "Fix header", CreatedAt: created, PublishedAt: created.Add(2 * time.Minute)},
+		{SiteID: "site-b", State: "error", Context: "deploy-preview", ErrorMessage: "Build script returned non-zero exit code", CreatedAt: created, UpdatedAt: created.Add(time.Minute)},
+	})
+
+	require.Equal(t, 2, frame.Rows())
+	assert.Equal(t, []any{created, created.Add(2 * time.Minute), "Deploy ready: my-site", "Branch: main\nCommit: abc123 Fix header", "ready,production"}, frame.RowCopy(0))
+	assert.Equal(t, []any{created, created.Add(time.Minute), "Deploy error: site-b", "Error: Build script returned non-zero exit code", "error,deploy-preview"}, frame.RowCopy(1))
+}
+
+func TestMergeDeploys(t *testing.T) {
+	updated := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	listed := client.DeploysResponse{
+		{ID: "deploy-1", State: "building", UpdatedAt: updated},
+		{ID: "deploy-2", State: "ready", UpdatedAt: updated},
+	}
+	notified := client.DeploysResponse{
+		{ID: "deploy-1", State: "ready", UpdatedAt: updated.Add(time.Minute)},
+		{ID: "deploy-2", State: "building", UpdatedAt: updated.Add(-time.Minute)},
+		{ID: "deploy-3", State: "enqueued", UpdatedAt: updated},
+	}
+
+	merged := mergeDeploys(listed, notified)
+	require.Len(t, merged, 3)
+	assert.Equal(t, "ready", merged[0].State)
+	assert.Equal(t, "ready", merged[1].State)
+	assert.Equal(t, "deploy-3", merged[2].ID)
+}
@@ -0,0 +1,185 @@
+package query
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+// minutesUsage sums the deploy time of a site, or of a branch and context of
+// a site.
+type minutesUsage struct {
+	site     string
+	siteName string
+	context  string
+	branch   string
+	seconds  int64
+	deploys  int64
+}
+
+// attributeMinutes sums the deploy time per site, and per context and
+// branch of every site. Both are ranked by the most minutes used.
+func attributeMinutes(deploys client.DeploysResponse) (sites []minutesUsage, branches []minutesUsage) {
+	type branchKey struct{ site, context, branch string }
+
+	perSite := map[string]*minutesUsage{}
+	perBranch := map[branchKey]*minutesUsage{}
+	for _, deploy := range deploys {
+		site, ok := perSite[deploy.SiteID]
+		if !ok {
+			site = &minutesUsage{site: deploy.SiteID}
+			perSite[deploy.SiteID] = site
+		}
+
+		key := branchKey{deploy.SiteID, deploy.Context, deploy.Branch}
+		branch, ok := perBranch[key]
+		if !ok {
+			branch = &minutesUsage{site: deploy.SiteID, context: deploy.Context, branch: deploy.Branch}
+			perBranch[key] = branch
+		}
+
+		for _, usage := range []*minutesUsage{site, branch} {
+			usage.seconds += deploy.DeployTime
+			usage.deploys++
+			if usage.siteName == "" {
+				usage.siteName = deploy.Name
+			}
+		}
+	}
+
+	return rankUsage(perSite), rankUsage(perBranch)
+}
+
+func rankUsage[K comparable](usages map[K]*minutesUsage) []minutesUsage {
+	ranked := make([]minutesUsage, 0, len(usages))
+	for _, usage := range usages {
+		ranked = append(ranked, *usage)
+	}
+
+	slices.SortFunc(ranked, func(a, b minutesUsage) int {
+		if order := cmp.Compare(b.seconds, a.seconds); order != 0 {
+			return order
+		}
+
+		if order := cmp.Compare(a.site, b.site); order != 0 {
+			return order
+		}
+
+		if order := cmp.Compare(a.context, b.context); order != 0 {
+			return order
+		}
+
+		return cmp.Compare(a.branch, b.branch)
+	})
+
+	return ranked
+}
+
+// usageLabels maps the label fields of the usage frames to the deploy fields
+// they come from.
+var usageLabels = map[string]string{
+	"Site":     "SiteID",
+	"SiteName": "Name",
+	"Context":  "Context",
+	"Branch":   "Branch",
+}
+
+// HandleBuildMinutesBySiteQuery attributes the build minutes of the current
+// billing period to the sites, and to their contexts and branches, from the
+// deploy time of their deploys. When the billing period is unknown, for
+// example without an account id, it covers the query time range instead.
+// Filters apply to the deploys.
+func (q QueryHandler) HandleBuildMinutesBySiteQuery(ctx context.Context, qm queryModel, siteIds []string) backend.DataResponse {
+	var response backend.DataResponse
+
+	var statusNotices []data.Notice
+	status, statusMeta, err := q.client.GetBuildAccountDetails(ctx)
+	if err != nil {
+		statusNotices = append(statusNotices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("The billing period is unknown, build minutes cover the query time range instead: %s", err.Error()),
+		})
+	} else if period := status.Minutes; !period.PeriodStartDate.IsZero() {
+		qm.TimeRange = backend.TimeRange{From: period.PeriodStartDate, To: period.PeriodEndDate}
+	}
+
+	ctx = client.WithSince(ctx, qm.TimeRange.From)
+	results := client.DoGets[client.DeploysResponse](ctx, q.deploysDoer(qm), siteIds, q.client.Concurrency)
+	notices, err := siteNotices(results, qm.ErrorMode)
+	if err != nil {
+		return errorResponse(err, "failed to get deployments")
+	}
+
+	deploys := inTimeRange(client.Flatten(results), qm.TimeRange, client.Deploy.Created)
+	deploys = filterRedactedRows(deploys, compileRedactions("deployments", q.client.Settings), qm.rowFilters)
+	sites, branches := attributeMinutes(deploys)
+	meta := results.Meta().Merge(statusMeta)
+
+	// Context and Branch only exist per branch, each frame keeps the selected
+	// fields it has
+	// the minutes are attributed per site and branch before they are redacted
+	labels := newLabelRedactor("deployments", q.client.Settings, usageLabels)
+
+	bySite := usageFrame("build_minutes_by_site", sites, false)
+	labels.redact(bySite)
+	selectFields(bySite, frameFields(bySite, qm.ParsingOptions.SelectedFields))
+	applyMeta(bySite, meta)
+	bySite.AppendNotices(append(statusNotices, notices...)...)
+
+	byBranch := usageFrame("build_minutes_by_branch", branches, true)
+	labels.redact(byBranch)
+	selectFields(byBranch, frameFields(byBranch, qm.ParsingOptions.SelectedFields))
+	applyMeta(byBranch, meta)
+
+	response.Frames = append(response.Frames, bySite, byBranch)
+
+	return response
+}
+
+// usageFrame returns the ranked usages with their share of the minutes of
+// every usage.
+func usageFrame(name string, usages []minutesUsage, perBranch bool) *data.Frame {
+	total := int64(0)
+	for _, usage := range usages {
+		total += usage.seconds
+	}
+
+	frame := data.NewFrame(name,
+		data.NewField("Rank", nil, []int64{}),
+		data.NewField("Site", nil, []string{}),
+		data.NewField("SiteName", nil, []string{}),
+	)
+	if perBranch {
+		frame.Fields = append(frame.Fields,
+			data.NewField("Context", nil, []string{}),
+			data.NewField("Branch", nil, []string{}),
+		)
+	}
+	frame.Fields = append(frame.Fields,
+		data.NewField("Minutes", nil, []float64{}),
+		data.NewField("Deploys", nil, []int64{}),
+		data.NewField("Share", nil, []*float64{}).SetConfig(&data.FieldConfig{DisplayName: "Share of total", Unit: "percentunit"}),
+	)
+
+	for i, usage := range usages {
+		var share *float64
+		if total > 0 {
+			s := float64(usage.seconds) / float64(total)
+			share = &s
+		}
+
+		row := []any{int64(i + 1), usage.site, usage.siteName}
+		if perBranch {
+			row = append(row, usage.context, usage.branch)
+		}
+		frame.AppendRow(append(row, float64(usage.seconds)/60, usage.deploys, share)...)
+	}
+
+	return frame
+}
@@ -0,0 +1,115 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+func TestBuildMinutesBySite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/my-account/builds/status":
+			fmt.Fprint(w, `{"minutes":{"current":10,"period_start_date":"2024-01-01T00:00:00Z","period_end_date":"2024-02-01T00:00:00Z"}}`)
+		case "/sites/site-a/deploys":
+			fmt.Fprint(w, `[
+				{"id":"a-3","name":"site-a-name","branch":"main","context":"production","deploy_time":120,"created_at":"2024-01-03T00:00:00Z"},
+				{"id":"a-2","name":"site-a-name","branch":"feature","context":"deploy-preview","deploy_time":60,"created_at":"2024-01-02T00:00:00Z"},
+				{"id":"a-1","name":"site-a-name","branch":"main","context":"production","deploy_time":600,"created_at":"2023-12-31T00:00:00Z"}
+			]`)
+		case "/sites/site-b/deploys":
+			fmt.Fprint(w, `[{"id":"b-1","name":"site-b-name","branch":"main","context":"production","deploy_time":420,"created_at":"2024-01-02T00:00:00Z"}]`)
+		}
+	}))
+	defer server.Close()
+
+	handler := NewQueryHandler(newTestClient(t, models.Settings{BaseUrl: server.URL, AccountId: "my-account"}), nil, nil, nil)
+
+	res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+		JSON: []byte(`{"entity":"build-minutes-by-site","siteId":"{site-a,site-b}"}`),
+	})
+	require.NoError(t, res.Error)
+	require.Len(t, res.Frames, 2)
+
+	bySite := res.Frames[0]
+	assert.Equal(t, []string{"site-b", "site-a"}, fieldValues[string](bySite.Fields[1]))
+	assert.Equal(t, []string{"site-b-name", "site-a-name"}, fieldValues[string](bySite.Fields[2]))
+	assert.Equal(t, []float64{7, 3}, fieldValues[float64](bySite.Fields[3]))
+
+	share, _ := bySite.FieldByName("Share")
+	require.NotNil(t, share)
+	assert.InDelta(t, 0.7, *share.At(0).(*float64), 0.001)
+
+	byBranch := res.Frames[1]
+	assert.Equal(t, []string{"site-b", "site-a", "site-a"}, fieldValues[string](byBranch.Fields[1]))
+	assert.Equal(t, []string{"main", "main", "feature"}, fieldValues[string](byBranch.Fields[4]))
+	assert.Equal(t, []float64{7, 2, 1}, fieldValues[float64](byBranch.Fields[5]))
+}
+
+func TestBuildMinutesBySiteFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sites/site-a/deploys":
+			fmt.Fprint(w, `[
+				{"id":"a-2","name":"site-a-name","branch":"main","context":"production","deploy_time":120,"created_at":"2024-01-03T00:00:00Z"},
+				{"id":"a-1","name":"site-a-name","branch":"main","context":"production","deploy_time":600,"created_at":"2023-12-31T00:00:00Z"}
+			]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	handler := NewQueryHandler(newTestClient(t, models.Settings{BaseUrl: server.URL}), nil, nil, nil)
+
+	t.Run("covers the query time range without an account id", func(t *testing.T) {
+		res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON: []byte(`{"entity":"build-minutes-by-site","siteId":"site-a"}`),
+			TimeRange: backend.TimeRange{
+				From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+		})
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 2)
+
+		bySite := res.Frames[0]
+		assert.Equal(t, []float64{2}, fieldValues[float64](bySite.Fields[3]))
+		require.NotNil(t, bySite.Meta)
+		require.Len(t, bySite.Meta.Notices, 1)
+		assert.Equal(t, data.NoticeSeverityWarning, bySite.Meta.Notices[0].Severity)
+		assert.Contains(t, bySite.Meta.Notices[0].Text, "query time range")
+	})
+
+	t.Run("selects the fields of each frame", func(t *testing.T) {
+		res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON: []byte(`{"entity":"build-minutes-by-site","siteId":"site-a","parsingOptions":{"selectedFields":["Site","Branch","Minutes"]}}`),
+			TimeRange: backend.TimeRange{
+				From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+		})
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 2)
+
+		names := func(frame *data.Frame) []string {
+			names := []string{}
+			for _, field := range frame.Fields {
+				names = append(names, field.Name)
+			}
+			return names
+		}
+		assert.Equal(t, []string{"Site", "Minutes"}, names(res.Frames[0]))
+		assert.Equal(t, []string{"Site", "Branch", "Minutes"}, names(res.Frames[1]))
+	})
+}
@@ -0,0 +1,335 @@
+package query
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+// doraAllSites is the site of the rows summing up every site.
+const doraAllSites = "All sites"
+
+// doraStats accumulates the DORA metrics of the production deploys of a site.
+type doraStats struct {
+	// deployments are the deploys that went live.
+	deployments int64
+	// failures are the deploys that failed.
+	failures int64
+	// rolledBack are the deploys that went live and were rolled back, they
+	// are counted in deployments too.
+	rolledBack int64
+	// leadTimes go from the start of the build to the publication.
+	leadTimes []time.Duration
+	// restores go from a failed deploy to the next one that went live.
+	restores []time.Duration
+}
+
+func (s *doraStats) add(other doraStats) {
+	s.deployments += other.deployments
+	s.failures += other.failures
+	s.rolledBack += other.rolledBack
+	s.leadTimes = append(s.leadTimes, other.leadTimes...)
+	s.restores = append(s.restores, other.restores...)
+}
+
+// changeFailureRate is the share of failed and rolled back deploys, nil
+// without deploys.
+func (s doraStats) changeFailureRate() *float64 {
+	total := s.deployments + s.failures
+	if total == 0 {
+		return nil
+	}
+
+	rate := float64(s.failures+s.rolledBack) / float64(total)
+	return &rate
+}
+
+// doraEvent is a production deploy counted in the stats of a time bucket.
+type doraEvent struct {
+	site  string
+	at    time.Time
+	stats doraStats
+}
+
+// doraEvents walks the production deploys of every site in creation order.
+// Netlify has no commit time, so lead time starts when the build, or the
+// deploy when the build is missing, was created. Deploys in the error state
+// and deploys that were rolled back count as failed changes.
+func doraEvents(deploys client.DeploysResponse, builds client.BuildsResponse) []doraEvent {
+	buildStarts := map[string]time.Time{}
+	for _, build := range builds {
+		buildStarts[build.ID] = build.CreatedAt
+		if start, ok := buildStarts[build.DeployID]; !ok || build.CreatedAt.Before(start) {
+			buildStarts[build.DeployID] = build.CreatedAt
+		}
+	}
+
+	production := slices.DeleteFunc(slices.Clone(deploys), func(d client.Deploy) bool { return d.Context != "production" })
+	slices.SortStableFunc(production, func(a, b client.Deploy) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	rolledBack, republished := rollbacks(production)
+
+	failedSince := map[string]time.Time{}
+	events := make([]doraEvent, 0, len(production))
+	for _, deploy := range production {
+		event := doraEvent{site: deploy.SiteID, at: deploy.CreatedAt}
+
+		switch deploy.State {
+		case "ready":
+			event.stats.deployments = 1
+
+			live := deploy.PublishedAt
+			if live.IsZero() {
+				live = deploy.UpdatedAt
+			}
+
+			start := deploy.CreatedAt
+			for _, id := range []string{deploy.Build_id, deploy.ID} {
+				if buildStart, ok := buildStarts[id]; ok && buildStart.Before(start) {
+					start = buildStart
+				}
+			}
+
+			// a rollback publishes the deploy again, its first publication
+			// is lost
+			if !live.IsZero() && live.After(start) && !republished[deploy.ID] {
+				event.stats.leadTimes = []time.Duration{live.Sub(start)}
+			}
+
+			if restored, ok := rolledBack[deploy.ID]; ok {
+				event.stats.rolledBack = 1
+				if !live.IsZero() && restored.After(live) {
+					event.stats.restores = append(event.stats.restores, restored.Sub(live))
+				}
+			}
+
+			if failed, ok := failedSince[deploy.SiteID]; ok {
+				if !live.IsZero() && live.After(failed) {
+					event.stats.restores = append(event.stats.restores, live.Sub(failed))
+				}
+				delete(failedSince, deploy.SiteID)
+			}
+		case "error":
+			event.stats.failures = 1
+			if _, ok := failedSince[deploy.SiteID]; !ok {
+				failedSince[deploy.SiteID] = deploy.CreatedAt
+			}
+		default:
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// rollbacks finds the production deploys of every site that were live when
+// an older deploy was published again, which is how Netlify rolls back. It
+// returns when each of them was rolled back, and the deploys published again.
+func rollbacks(production client.DeploysResponse) (rolledBack map[string]time.Time, republished map[string]bool) {
+	published := slices.DeleteFunc(slices.Clone(production), func(d client.Deploy) bool {
+		return d.State != "ready" || d.PublishedAt.IsZero()
+	})
+	slices.SortStableFunc(published, func(a, b client.Deploy) int { return a.PublishedAt.Compare(b.PublishedAt) })
+
+	rolledBack = map[string]time.Time{}
+	republished = map[string]bool{}
+	live := map[string]client.Deploy{}
+	for _, deploy := range published {
+		if current, ok := live[deploy.SiteID]; ok && deploy.CreatedAt.Before(current.CreatedAt) {
+			rolledBack[current.ID] = deploy.PublishedAt
+			republished[deploy.ID] = true
+		}
+
+		live[deploy.SiteID] = deploy
+	}
+
+	return rolledBack, republished
+}
+
+// doraLabels maps the label fields of the DORA frames to the deploy fields
+// they come from.
+var doraLabels = map[string]string{"Site": "SiteID"}
+
+// HandleDoraQuery computes the four DORA metrics of the production deploys
+// of every site over the query time range: a table per site and for all
+// sites, and the same metrics per interval as a long time series. Filters
+// apply to the deploys, e.g. Branch == main.
+func (q QueryHandler) HandleDoraQuery(ctx context.Context, qm queryModel, siteIds []string) backend.DataResponse {
+	var response backend.DataResponse
+
+	ctx = client.WithSince(ctx, qm.TimeRange.From)
+	deployResults := client.DoGets[client.DeploysResponse](ctx, q.deploysDoer(qm), siteIds, q.client.Concurrency)
+	deployNotices, err := siteNotices(deployResults, qm.ErrorMode)
+	if err != nil {
+		return errorResponse(err, "failed to get deployments")
+	}
+
+	buildResults := client.DoGets[client.BuildsResponse](ctx, q.buildsDoer(qm), siteIds, q.client.Concurrency)
+	buildNotices, err := siteNotices(buildResults, qm.ErrorMode)
+	if err != nil {
+		return errorResponse(err, "failed to get builds")
+	}
+
+	deploys := inTimeRange(client.Flatten(deployResults), qm.TimeRange, client.Deploy.Created)
+	deploys = filterRedactedRows(deploys, compileRedactions("deployments", q.client.Settings), qm.rowFilters)
+	events := doraEvents(deploys, client.Flatten(buildResults))
+	meta := deployResults.Meta().Merge(buildResults.Meta())
+
+	from, to := qm.TimeRange.From, qm.TimeRange.To
+	if from.IsZero() || to.IsZero() {
+		from, to = rowsTimeRange(deploys, client.Deploy.Created)
+	}
+
+	// the metrics are computed per site before the sites are redacted
+	labels := newLabelRedactor("deployments", q.client.Settings, doraLabels)
+
+	stats := doraFrame(events, to.Sub(from))
+	labels.redact(stats, doraAllSites)
+	selectFields(stats, qm.ParsingOptions.SelectedFields)
+	applyMeta(stats, meta)
+	stats.AppendNotices(append(deployNotices, buildNotices...)...)
+
+	series := doraTimeSeries(events, from, to, qm.Interval)
+	labels.redact(series, doraAllSites)
+	meta.Truncated = false
+	applyMeta(series, meta)
+
+	response.Frames = append(response.Frames, stats, series)
+
+	return response
+}
+
+// doraFrame sums the events up per site and for every site.
+func doraFrame(events []doraEvent, span time.Duration) *data.Frame {
+	perSite := map[string]*doraStats{}
+	sites := []string{}
+	all := doraStats{}
+	for _, event := range events {
+		if _, ok := perSite[event.site]; !ok {
+			perSite[event.site] = &doraStats{}
+			sites = append(sites, event.site)
+		}
+
+		perSite[event.site].add(event.stats)
+		all.add(event.stats)
+	}
+
+	slices.Sort(sites)
+
+	frame := newDoraFrame("dora", false)
+	for _, site := range append(sites, doraAllSites) {
+		s := all
+		if site != doraAllSites {
+			s = *perSite[site]
+		}
+
+		var frequency *float64
+		if days := span.Hours() / 24; days > 0 {
+			perDay := float64(s.deployments) / days
+			frequency = &perDay
+		}
+
+		frame.AppendRow(site, s.deployments, frequency, medianSeconds(s.leadTimes), s.changeFailureRate(), medianSeconds(s.restores))
+	}
+
+	return frame
+}
+
+// doraTimeSeries buckets the events into the query interval, per site and
+// for every site.
+func doraTimeSeries(events []doraEvent, from time.Time, to time.Time, interval time.Duration) *data.Frame {
+	interval = bucketInterval(from, to, interval, 0)
+	from = from.Truncate(interval)
+	buckets := timeBuckets(from, to, interval)
+
+	sites := []string{}
+	perSite := map[string][]doraStats{}
+	for _, event := range events {
+		if event.at.Before(from) || event.at.After(to) {
+			continue
+		}
+
+		if _, ok := perSite[event.site]; !ok {
+			perSite[event.site] = make([]doraStats, len(buckets))
+			sites = append(sites, event.site)
+		}
+
+		bucket := event.at.Sub(from) / interval
+		perSite[event.site][bucket].add(event.stats)
+	}
+
+	slices.Sort(sites)
+
+	all := make([]doraStats, len(buckets))
+	for _, site := range sites {
+		for i := range buckets {
+			all[i].add(perSite[site][i])
+		}
+	}
+	perSite[doraAllSites] = all
+
+	frame := newDoraFrame("dora_timeseries", true)
+	frame.Meta = &data.FrameMeta{Type: data.FrameTypeTimeSeriesLong}
+	for i, bucket := range buckets {
+		for _, site := range append(sites, doraAllSites) {
+			s := perSite[site][i]
+			frame.AppendRow(bucket, site, s.deployments, medianSeconds(s.leadTimes), s.changeFailureRate(), medianSeconds(s.restores))
+		}
+	}
+
+	return frame
+}
+
+// newDoraFrame returns an empty frame of DORA metrics. Time series count the
+// deployments per interval, tables report them per day.
+func newDoraFrame(name string, timeSeries bool) *data.Frame {
+	frame := data.NewFrame(name)
+	if timeSeries {
+		frame.Fields = append(frame.Fields, data.NewField("Time", nil, []time.Time{}))
+	}
+
+	frame.Fields = append(frame.Fields,
+		data.NewField("Site", nil, []string{}),
+		data.NewField("Deployments", nil, []int64{}),
+	)
+
+	if !timeSeries {
+		frame.Fields = append(frame.Fields, data.NewField("DeploymentFrequency", nil, []*float64{}).SetConfig(&data.FieldConfig{
+			DisplayName: "Deployment frequency (per day)",
+		}))
+	}
+
+	frame.Fields = append(frame.Fields,
+		data.NewField("LeadTime", nil, []*float64{}).SetConfig(&data.FieldConfig{DisplayName: "Lead time for changes", Unit: "s"}),
+		data.NewField("ChangeFailureRate", nil, []*float64{}).SetConfig(&data.FieldConfig{DisplayName: "Change failure rate", Unit: "percentunit"}),
+		data.NewField("TimeToRestore", nil, []*float64{}).SetConfig(&data.FieldConfig{DisplayName: "Time to restore service", Unit: "s"}),
+	)
+
+	return frame
+}
+
+// medianSeconds returns the median duration in seconds, nil without
+// durations.
+func medianSeconds(durations []time.Duration) *float64 {
+	if len(durations) == 0 {
+		return nil
+	}
+
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + median) / 2
+	}
+
+	seconds := median.Seconds()
+	return &seconds
+}
@@ -0,0 +1,119 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+func TestDoraQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sites/site-a/deploys":
+			fmt.Fprint(w, `[
+				{"id":"a-4","build_id":"build-a-4","state":"ready","context":"production","created_at":"2024-01-02T12:00:00Z","published_at":"2024-01-02T12:10:00Z"},
+				{"id":"a-3","state":"ready","context":"deploy-preview","created_at":"2024-01-02T09:00:00Z","published_at":"2024-01-02T09:05:00Z"},
+				{"id":"a-2","state":"error","context":"production","created_at":"2024-01-02T10:00:00Z"},
+				{"id":"a-1","state":"ready","context":"production","created_at":"2024-01-01T10:00:00Z","published_at":"2024-01-01T10:02:00Z"}
+			]`)
+		case "/sites/site-a/builds":
+			fmt.Fprint(w, `[{"id":"build-a-4","deploy_id":"a-4","created_at":"2024-01-02T11:50:00Z"}]`)
+		case "/sites/site-b/deploys":
+			fmt.Fprint(w, `[{"id":"b-1","state":"ready","context":"production","created_at":"2024-01-01T08:00:00Z","published_at":"2024-01-01T08:04:00Z"}]`)
+		case "/sites/site-b/builds":
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	handler := NewQueryHandler(newTestClient(t, models.Settings{BaseUrl: server.URL}), nil, nil, nil)
+
+	res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+		JSON:      []byte(`{"entity":"dora","siteId":"{site-a,site-b}"}`),
+		TimeRange: backend.TimeRange{From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+		Interval:  24 * time.Hour,
+	})
+	require.NoError(t, res.Error)
+	require.Len(t, res.Frames, 2)
+
+	stats := res.Frames[0]
+	require.Equal(t, 3, stats.Rows())
+
+	row := func(i int) map[string]any {
+		values := map[string]any{}
+		for _, field := range stats.Fields {
+			values[field.Name] = field.At(i)
+		}
+		return values
+	}
+
+	siteA := row(0)
+	assert.Equal(t, "site-a", siteA["Site"])
+	assert.Equal(t, int64(2), siteA["Deployments"])
+	assert.Equal(t, 1.0, *siteA["DeploymentFrequency"].(*float64))
+	// 2 minutes and 20 minutes from the build start
+	assert.Equal(t, 660.0, *siteA["LeadTime"].(*float64))
+	assert.InDelta(t, 1.0/3, *siteA["ChangeFailureRate"].(*float64), 0.001)
+	// from the failed deploy at 10:00 to the fix going live at 12:10
+	assert.Equal(t, 7800.0, *siteA["TimeToRestore"].(*float64))
+
+	siteB := row(1)
+	assert.Equal(t, "site-b", siteB["Site"])
+	assert.Equal(t, 0.0, *siteB["ChangeFailureRate"].(*float64))
+	assert.Nil(t, siteB["TimeToRestore"])
+
+	all := row(2)
+	assert.Equal(t, doraAllSites, all["Site"])
+	assert.Equal(t, int64(3), all["Deployments"])
+	assert.Equal(t, 0.25, *all["ChangeFailureRate"].(*float64))
+
+	series := res.Frames[1]
+	assert.Equal(t, data.FrameTypeTimeSeriesLong, series.Meta.Type)
+	// site-a, site-b and all sites for the two days and the midnight the range
+	// ends on
+	assert.Equal(t, 9, series.Rows())
+}
+
+func TestDoraRollbacks(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC) }
+	deploys := client.DeploysResponse{
+		// d-1 is published again at 15:00 to roll d-2 back
+		{ID: "d-1", SiteID: "site-a", State: "ready", Context: "production", CreatedAt: at(8), PublishedAt: at(15)},
+		{ID: "d-2", SiteID: "site-a", State: "ready", Context: "production", CreatedAt: at(12), PublishedAt: at(13)},
+	}
+
+	events := doraEvents(deploys, nil)
+	require.Len(t, events, 2)
+
+	stats := doraStats{}
+	for _, event := range events {
+		stats.add(event.stats)
+	}
+
+	assert.Equal(t, int64(2), stats.deployments)
+	assert.Equal(t, int64(1), stats.rolledBack)
+	assert.Equal(t, 0.5, *stats.changeFailureRate())
+	assert.Equal(t, []time.Duration{2 * time.Hour}, stats.restores)
+	// the first publication of d-1 is unknown
+	assert.Equal(t, []time.Duration{time.Hour}, stats.leadTimes)
+}
+
+func TestDoraTimeSeriesBuckets(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []doraEvent{{site: "site-a", at: from.Add(time.Hour), stats: doraStats{deployments: 1}}}
+
+	// an alerting query over a month comes with a one second interval
+	series := doraTimeSeries(events, from, from.AddDate(0, 0, 30), time.Second)
+	assert.LessOrEqual(t, series.Rows(), 2*(maxBuckets+1))
+}
@@ -0,0 +1,59 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+// errorResponse turns an error returned by the client into a data response
+// with the status and error source matching the cause, so a revoked token
+// can be told apart from a bad query.
+func errorResponse(err error, message string) backend.DataResponse {
+	status, source := errorStatus(err)
+
+	return backend.ErrDataResponseWithSource(status, source, fmt.Sprintf("%s: %v", message, err.Error()))
+}
+
+func errorStatus(err error) (backend.Status, backend.ErrorSource) {
+	var apiErr *client.APIError
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, client.ErrUnauthorized):
+		return backend.StatusUnauthorized, backend.ErrorSourceDownstream
+	case errors.Is(err, client.ErrForbidden):
+		return backend.StatusForbidden, backend.ErrorSourceDownstream
+	case errors.Is(err, client.ErrNotFound):
+		return backend.StatusNotFound, backend.ErrorSourceDownstream
+	case errors.Is(err, client.ErrRateLimited):
+		return backend.StatusTooManyRequests, backend.ErrorSourceDownstream
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return backend.StatusTimeout, backend.ErrorSourceDownstream
+	case errors.Is(err, context.Canceled):
+		// a closed dashboard or a newer refresh cancelled the query
+		return backend.StatusTimeout, backend.ErrorSourceDownstream
+	case errors.Is(err, client.ErrUpstreamUnavailable):
+		return backend.StatusBadGateway, backend.ErrorSourceDownstream
+	case errors.As(err, &apiErr):
+		return backend.StatusBadRequest, backend.ErrorSourceFromHTTPStatus(apiErr.StatusCode)
+	default:
+		return backend.StatusInternal, backend.ErrorSourcePlugin
+	}
+}
+
+// badRequest is the response for queries the plugin cannot run as sent.
+func badRequest(format string, args ...any) backend.DataResponse {
+	return backend.ErrDataResponseWithSource(backend.StatusBadRequest, backend.ErrorSourcePlugin, fmt.Sprintf(format, args...))
+}
+
+// conversionError is the response for results that could not be turned into
+// data frames.
+func conversionError(err error, message string) backend.DataResponse {
+	return backend.ErrDataResponseWithSource(backend.StatusInternal, backend.ErrorSourcePlugin, fmt.Sprintf("%s: %v", message, err.Error()))
+}
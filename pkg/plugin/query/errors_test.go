@@ -0,0 +1,88 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+func newTestClient(t *testing.T, settings models.Settings) client.Client {
+	t.Helper()
+
+	c, err := client.NewClient(settings, httpclient.Options{})
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		status         int
+		body           string
+		expectedStatus backend.Status
+		expectedSource backend.ErrorSource
+	}{
+		{http.StatusUnauthorized, `{"code":401,"message":"Access Denied"}`, backend.StatusUnauthorized, backend.ErrorSourceDownstream},
+		{http.StatusForbidden, `{"code":403,"message":"Forbidden"}`, backend.StatusForbidden, backend.ErrorSourceDownstream},
+		{http.StatusNotFound, `{"code":404,"message":"Not Found"}`, backend.StatusNotFound, backend.ErrorSourceDownstream},
+		{http.StatusTooManyRequests, ``, backend.StatusTooManyRequests, backend.ErrorSourceDownstream},
+		{http.StatusServiceUnavailable, `upstream connect error`, backend.StatusBadGateway, backend.ErrorSourceDownstream},
+		{http.StatusUnprocessableEntity, `{"code":422,"message":"Invalid"}`, backend.StatusBadRequest, backend.ErrorSourceDownstream},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			c := newTestClient(t, models.Settings{BaseUrl: server.URL})
+			_, _, err := c.GetAccounts(context.Background())
+
+			res := errorResponse(err, "failed to get accounts")
+			assert.Equal(t, tt.expectedStatus, res.Status)
+			assert.Equal(t, tt.expectedSource, res.ErrorSource)
+		})
+	}
+
+	t.Run("uses the message of the error body", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"code":401,"message":"Access Denied"}`)
+		}))
+		defer server.Close()
+
+		c := newTestClient(t, models.Settings{BaseUrl: server.URL})
+		_, _, err := c.GetAccounts(context.Background())
+
+		var apiErr *client.APIError
+		assert.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, "Access Denied", apiErr.Message)
+	})
+
+	t.Run("timeouts and plugin errors", func(t *testing.T) {
+		res := errorResponse(context.DeadlineExceeded, "failed to get sites")
+		assert.Equal(t, backend.StatusTimeout, res.Status)
+
+		res = errorResponse(fmt.Errorf("get sites: %w", context.Canceled), "failed to get sites")
+		assert.Equal(t, backend.StatusTimeout, res.Status)
+		assert.Equal(t, backend.ErrorSourceDownstream, res.ErrorSource)
+
+		res = errorResponse(errors.New("unexpected end of JSON input"), "failed to get sites")
+		assert.Equal(t, backend.StatusInternal, res.Status)
+		assert.Equal(t, backend.ErrorSourcePlugin, res.ErrorSource)
+	})
+}
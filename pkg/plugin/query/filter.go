@@ -0,0 +1,263 @@
+package query
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter operators, numeric comparisons also accept time fields compared
+// to RFC 3339 values.
+const (
+	operatorEquals       = "=="
+	operatorNotEquals    = "!="
+	operatorRegex        = "=~"
+	operatorIn           = "in"
+	operatorGreater      = ">"
+	operatorGreaterEqual = ">="
+	operatorLess         = "<"
+	operatorLessEqual    = "<="
+)
+
+// filter is a row condition of a query, e.g. {"field":"state","operator":"==","value":"error"}.
+type filter struct {
+	Field    string `json:"field"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+	// Values are the accepted values of the in operator, a comma separated
+	// Value is used when empty.
+	Values []string `json:"values"`
+}
+
+// rowFilter is a filter validated against the schema of an entity.
+type rowFilter struct {
+	field    fieldRef
+	operator string
+	values   []string
+	regex    *regexp.Regexp
+	number   float64
+	time     time.Time
+}
+
+// rowEntities maps the entities computing their frames to the entity of the
+// rows they are computed from, their filters apply to those rows, e.g. DORA
+// metrics of a single branch.
+var rowEntities = map[string]string{
+	"dora":                   "deployments",
+	"build-minutes-by-site":  "deployments",
+	"build-minutes-forecast": "builds-account",
+}
+
+// compileFilters validates the filters of a query against the schema of its
+// entity, or of the rows a computed entity is made of.
+func compileFilters(entity string, filters []filter) ([]rowFilter, error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+
+	s, ok := entitySchemas[entity]
+	if rowEntity, computed := rowEntities[entity]; computed {
+		s, ok = entitySchemas[rowEntity]
+	}
+	if !ok {
+		return nil, fmt.Errorf("entity %q does not support filters", entity)
+	}
+
+	compiled := make([]rowFilter, 0, len(filters))
+	for _, f := range filters {
+		field, err := s.field(entity, f.Field)
+		if err != nil {
+			return nil, err
+		}
+
+		rf := rowFilter{field: field, operator: f.Operator, values: []string{f.Value}}
+
+		switch f.Operator {
+		case operatorEquals, operatorNotEquals:
+		case operatorIn:
+			rf.values = f.Values
+			if len(rf.values) == 0 {
+				rf.values = strings.Split(f.Value, ",")
+				for i := range rf.values {
+					rf.values[i] = strings.TrimSpace(rf.values[i])
+				}
+			}
+		case operatorRegex:
+			rf.regex, err = regexp.Compile(f.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex for field %q: %w", f.Field, err)
+			}
+		case operatorGreater, operatorGreaterEqual, operatorLess, operatorLessEqual:
+			if err := rf.compileComparison(f.Value); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown operator %q for field %q, expected one of: %s", f.Operator, f.Field,
+				strings.Join([]string{operatorEquals, operatorNotEquals, operatorRegex, operatorIn, operatorGreater, operatorGreaterEqual, operatorLess, operatorLessEqual}, ", "))
+		}
+
+		compiled = append(compiled, rf)
+	}
+
+	return compiled, nil
+}
+
+func (f *rowFilter) compileComparison(value string) error {
+	if f.field.typ == reflect.TypeOf(time.Time{}) {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return fmt.Errorf("field %q is a time, %q is not an RFC 3339 time", f.field.name(), value)
+		}
+
+		f.time = t
+		return nil
+	}
+
+	if !f.field.dynamic && !isNumeric(f.field.typ.Kind()) {
+		return fmt.Errorf("operator %q needs a numeric or time field, %q is not", f.operator, f.field.name())
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("operator %q needs a number, got %q", f.operator, value)
+	}
+
+	f.number = number
+	return nil
+}
+
+// match reports whether row passes the filter.
+func (f rowFilter) match(row reflect.Value) bool {
+	value := f.field.value(row)
+
+	switch f.operator {
+	case operatorEquals:
+		return formatValue(value) == f.values[0]
+	case operatorNotEquals:
+		return formatValue(value) != f.values[0]
+	case operatorIn:
+		return slices.Contains(f.values, formatValue(value))
+	case operatorRegex:
+		return f.regex.MatchString(formatValue(value))
+	}
+
+	if !value.IsValid() {
+		return false
+	}
+
+	var order int
+	if t, ok := value.Interface().(time.Time); ok {
+		order = t.Compare(f.time)
+	} else {
+		number, ok := numberValue(value)
+		if !ok {
+			return false
+		}
+
+		order = cmp.Compare(number, f.number)
+	}
+
+	switch f.operator {
+	case operatorGreater:
+		return order > 0
+	case operatorGreaterEqual:
+		return order >= 0
+	case operatorLess:
+		return order < 0
+	default:
+		return order <= 0
+	}
+}
+
+// filterRows keeps the rows passing every filter.
+func filterRows[T ~[]E, E any](rows T, filters []rowFilter) T {
+	if len(filters) == 0 {
+		return rows
+	}
+
+	filtered := make(T, 0, len(rows))
+	for _, row := range rows {
+		if matchFilters(reflect.ValueOf(row), filters) {
+			filtered = append(filtered, row)
+		}
+	}
+
+	return filtered
+}
+
+// filterRedactedRows keeps the rows whose redacted values pass every filter,
+// for rows computed on unredacted. Filters cannot match redacted values.
+func filterRedactedRows[T ~[]E, E any](rows T, redactions []fieldRef, filters []rowFilter) T {
+	if len(filters) == 0 {
+		return rows
+	}
+
+	redacted := redactRows(rows, redactions)
+	filtered := make(T, 0, len(rows))
+	for i, row := range rows {
+		if matchFilters(reflect.ValueOf(redacted[i]), filters) {
+			filtered = append(filtered, row)
+		}
+	}
+
+	return filtered
+}
+
+// matchFilters reports whether row passes every filter.
+func matchFilters(row reflect.Value, filters []rowFilter) bool {
+	return !slices.ContainsFunc(filters, func(f rowFilter) bool { return !f.match(row) })
+}
+
+// formatValue returns the text a filter value is compared to, missing map
+// keys are empty.
+func formatValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+
+	switch value := v.Interface().(type) {
+	case string:
+		return value
+	case time.Time:
+		return value.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func isNumeric(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// numberValue returns v as a number, strings such as map values are parsed.
+func numberValue(v reflect.Value) (float64, bool) {
+	if !v.IsValid() {
+		return 0, false
+	}
+
+	switch {
+	case v.CanInt():
+		return float64(v.Int()), true
+	case v.CanUint():
+		return float64(v.Uint()), true
+	case v.CanFloat():
+		return v.Float(), true
+	case v.Kind() == reflect.String:
+		number, err := strconv.ParseFloat(v.String(), 64)
+		return number, err == nil
+	default:
+		return 0, false
+	}
+}
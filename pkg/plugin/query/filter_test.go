@@ -0,0 +1,154 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+func TestFilterRows(t *testing.T) {
+	deploys := client.DeploysResponse{
+		{ID: "deploy-1", State: "error", Branch: "main", Context: "production", DeployTime: 30, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: "deploy-2", State: "ready", Branch: "release/1.2", Context: "branch-deploy", DeployTime: 90, CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{ID: "deploy-3", State: "error", Branch: "release/1.3", Context: "branch-deploy", DeployTime: 120, CreatedAt: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+	}
+
+	ids := func(deploys client.DeploysResponse) []string {
+		ids := []string{}
+		for _, deploy := range deploys {
+			ids = append(ids, deploy.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name     string
+		filters  []filter
+		expected []string
+	}{
+		{"equals", []filter{{Field: "state", Operator: "==", Value: "error"}, {Field: "Context", Operator: "==", Value: "production"}}, []string{"deploy-1"}},
+		{"not equals", []filter{{Field: "State", Operator: "!=", Value: "error"}}, []string{"deploy-2"}},
+		{"regex", []filter{{Field: "branch", Operator: "=~", Value: "^release/"}}, []string{"deploy-2", "deploy-3"}},
+		{"in", []filter{{Field: "id", Operator: "in", Value: "deploy-1, deploy-3"}}, []string{"deploy-1", "deploy-3"}},
+		{"in values", []filter{{Field: "id", Operator: "in", Values: []string{"deploy-2"}}}, []string{"deploy-2"}},
+		{"numeric", []filter{{Field: "deploy_time", Operator: ">=", Value: "90"}}, []string{"deploy-2", "deploy-3"}},
+		{"time", []filter{{Field: "created_at", Operator: "<", Value: "2024-01-02T00:00:00Z"}}, []string{"deploy-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters, err := compileFilters("deployments", tt.filters)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ids(filterRows(deploys, filters)))
+		})
+	}
+
+	t.Run("filters map fields", func(t *testing.T) {
+		submissions := client.FormSubmissionsResponse{
+			{Id: "submission-1", Data: map[string]string{"rating": "4"}},
+			{Id: "submission-2", Data: map[string]string{"rating": "2"}},
+			{Id: "submission-3"},
+		}
+
+		filters, err := compileFilters("form-submissions", []filter{{Field: "data.rating", Operator: ">", Value: "3"}})
+		require.NoError(t, err)
+		filtered := filterRows(submissions, filters)
+		require.Len(t, filtered, 1)
+		assert.Equal(t, "submission-1", filtered[0].Id)
+	})
+}
+
+func TestCompileFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  filter
+		message string
+	}{
+		{"unknown field", filter{Field: "Commit", Operator: "==", Value: "abc"}, `unknown field "Commit" for entity "builds"`},
+		{"unknown operator", filter{Field: "Sha", Operator: "~~", Value: "abc"}, `unknown operator "~~"`},
+		{"invalid regex", filter{Field: "Sha", Operator: "=~", Value: "("}, `invalid regex for field "Sha"`},
+		{"comparison on text", filter{Field: "Sha", Operator: ">", Value: "1"}, `needs a numeric or time field`},
+		{"invalid time", filter{Field: "CreatedAt", Operator: ">", Value: "yesterday"}, `not an RFC 3339 time`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileFilters("builds", []filter{tt.filter})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestComputedEntityFilters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/my-account/builds/status":
+			fmt.Fprint(w, `{"active":1,"minutes":{"current":10,"period_start_date":"2024-01-01T00:00:00Z","period_end_date":"2024-02-01T00:00:00Z"}}`)
+		case "/sites/site-a/deploys":
+			fmt.Fprint(w, `[
+				{"id":"a-2","branch":"feature","state":"ready","context":"production","deploy_time":60,"created_at":"2024-01-03T00:00:00Z","published_at":"2024-01-03T00:01:00Z"},
+				{"id":"a-1","branch":"main","state":"ready","context":"production","deploy_time":120,"created_at":"2024-01-02T00:00:00Z","published_at":"2024-01-02T00:01:00Z"}
+			]`)
+		case "/sites/site-a/builds":
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer server.Close()
+
+	query := func(t *testing.T, settings models.Settings, entity string, filters string) data.Frames {
+		settings.BaseUrl, settings.AccountId = server.URL, "my-account"
+		res := NewQueryHandler(newTestClient(t, settings), nil, nil, nil).Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON:      []byte(fmt.Sprintf(`{"entity":%q,"siteId":"site-a","filters":%s}`, entity, filters)),
+			TimeRange: backend.TimeRange{From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		})
+		require.NoError(t, res.Error)
+		return res.Frames
+	}
+	mainBranch := `[{"field":"branch","operator":"==","value":"main"}]`
+
+	t.Run("dora of a branch", func(t *testing.T) {
+		stats := query(t, models.Settings{}, "dora", mainBranch)[0]
+		deployments, _ := stats.FieldByName("Deployments")
+		assert.Equal(t, []int64{1, 1}, fieldValues[int64](deployments))
+	})
+
+	t.Run("build minutes of a branch", func(t *testing.T) {
+		bySite := query(t, models.Settings{}, "build-minutes-by-site", mainBranch)[0]
+		minutes, _ := bySite.FieldByName("Minutes")
+		assert.Equal(t, []float64{2}, fieldValues[float64](minutes))
+	})
+
+	t.Run("filters cannot match redacted values", func(t *testing.T) {
+		stats := query(t, models.Settings{RedactFields: []string{"Branch"}}, "dora", mainBranch)[0]
+		deployments, _ := stats.FieldByName("Deployments")
+		assert.Equal(t, []int64{0}, fieldValues[int64](deployments))
+	})
+
+	t.Run("filters can drop the forecast", func(t *testing.T) {
+		frames := query(t, models.Settings{}, "build-minutes-forecast", `[{"field":"active","operator":">","value":"5"}]`)
+		require.Len(t, frames, 1)
+		assert.Zero(t, frames[0].Rows())
+
+		frames = query(t, models.Settings{}, "build-minutes-forecast", `[{"field":"active","operator":"==","value":"1"}]`)
+		require.Len(t, frames, 2)
+		assert.NotZero(t, frames[0].Rows())
+	})
+
+	t.Run("rejects fields the rows do not have", func(t *testing.T) {
+		_, err := compileFilters("dora", []filter{{Field: "LeadTime", Operator: ">", Value: "1"}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown field "LeadTime" for entity "dora"`)
+	})
+}
@@ -0,0 +1,211 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/sampler"
+)
+
+// recentWindow is how far back the build status samples go to measure the
+// recent rate of consumption.
+var recentWindow = 24 * time.Hour
+
+const (
+	forecastLinear = "linear"
+	forecastRecent = "recent"
+)
+
+// minutesForecast projects the build minutes used by the end of the billing
+// period at a constant rate.
+type minutesForecast struct {
+	method string
+	// rate is in minutes per hour.
+	rate      float64
+	projected float64
+	overage   float64
+	// exhaustion is when the included minutes run out, nil when they last
+	// the period.
+	exhaustion *time.Time
+}
+
+// forecastMinutes projects the build minutes at the average rate of the
+// period so far, and at the rate of the samples of the recent window when
+// there are any.
+func forecastMinutes(status client.BuildAccountResponse, samples []sampler.Sample, now time.Time) []minutesForecast {
+	minutes := status.Minutes
+	forecasts := []minutesForecast{}
+
+	if elapsed := now.Sub(minutes.PeriodStartDate).Hours(); elapsed > 0 {
+		forecasts = append(forecasts, projectMinutes(status, forecastLinear, float64(minutes.Current)/elapsed, now))
+	}
+
+	recent := []sampler.Sample{}
+	for _, sample := range samples {
+		if !sample.Time.Before(minutes.PeriodStartDate) && !sample.Time.Before(now.Add(-recentWindow)) {
+			recent = append(recent, sample)
+		}
+	}
+
+	if len(recent) >= 2 {
+		first, last := recent[0], recent[len(recent)-1]
+		if span := last.Time.Sub(first.Time).Hours(); span > 0 {
+			forecasts = append(forecasts, projectMinutes(status, forecastRecent, float64(last.MinutesCurrent-first.MinutesCurrent)/span, now))
+		}
+	}
+
+	return forecasts
+}
+
+func projectMinutes(status client.BuildAccountResponse, method string, rate float64, now time.Time) minutesForecast {
+	minutes := status.Minutes
+	current := float64(minutes.Current)
+	included := float64(includedMinutes(status))
+
+	forecast := minutesForecast{method: method, rate: rate, projected: current}
+	if remaining := minutes.PeriodEndDate.Sub(now).Hours(); remaining > 0 {
+		forecast.projected += rate * remaining
+	}
+
+	if included > 0 {
+		forecast.overage = max(forecast.projected-included, 0)
+
+		switch {
+		case current >= included:
+			forecast.exhaustion = &now
+		case rate > 0:
+			exhaustion := now.Add(time.Duration((included - current) / rate * float64(time.Hour)))
+			if exhaustion.Before(minutes.PeriodEndDate) {
+				forecast.exhaustion = &exhaustion
+			}
+		}
+	}
+
+	return forecast
+}
+
+// includedMinutes are the minutes of the plan and of the extra packs bought.
+func includedMinutes(status client.BuildAccountResponse) int64 {
+	if status.Minutes.IncludedMinutesWithPacks > 0 {
+		return status.Minutes.IncludedMinutesWithPacks
+	}
+
+	return status.Minutes.IncludedMinutes
+}
+
+// HandleBuildMinutesForecastQuery projects the build minutes of the account
+// to the end of the billing period: a table per forecast method, and a
+// series of the actual minutes sampled so far followed by each forecast.
+func (q QueryHandler) HandleBuildMinutesForecastQuery(ctx context.Context, qm queryModel) backend.DataResponse {
+	var response backend.DataResponse
+
+	status, meta, err := q.client.GetBuildAccountDetails(ctx)
+	if err != nil {
+		return errorResponse(err, "failed to get build account details")
+	}
+
+	status = Redact("builds-account", []client.BuildAccountResponse{status}, q.client.Settings)[0]
+
+	// the build status is a single row, filters can drop its forecasts
+	if len(filterRows([]client.BuildAccountResponse{status}, qm.rowFilters)) == 0 {
+		table := forecastFrame(status, nil)
+		applyMeta(table, meta)
+		response.Frames = append(response.Frames, table)
+		return response
+	}
+
+	now := time.Now().UTC()
+	samples := redactRows(q.samples.Samples(status.Minutes.PeriodStartDate, now), sampleRedactions(q.client.Settings))
+	forecasts := forecastMinutes(status, samples, now)
+
+	table := forecastFrame(status, forecasts)
+	selectFields(table, qm.ParsingOptions.SelectedFields)
+	applyMeta(table, meta)
+
+	series := forecastSeries(status, samples, forecasts, now)
+	applyMeta(series, meta)
+
+	response.Frames = append(response.Frames, table, series)
+
+	return response
+}
+
+func forecastFrame(status client.BuildAccountResponse, forecasts []minutesForecast) *data.Frame {
+	frame := data.NewFrame("build_minutes_forecast",
+		data.NewField("Method", nil, []string{}),
+		data.NewField("Current", nil, []int64{}),
+		data.NewField("Included", nil, []int64{}),
+		data.NewField("Rate", nil, []float64{}).SetConfig(&data.FieldConfig{DisplayName: "Rate (minutes per day)"}),
+		data.NewField("Projected", nil, []float64{}),
+		data.NewField("Overage", nil, []float64{}),
+		data.NewField("Exhaustion", nil, []*time.Time{}),
+		data.NewField("PeriodEnd", nil, []time.Time{}),
+	)
+
+	for _, forecast := range forecasts {
+		frame.AppendRow(forecast.method, status.Minutes.Current, includedMinutes(status), forecast.rate*24, forecast.projected, forecast.overage, forecast.exhaustion, status.Minutes.PeriodEndDate)
+	}
+
+	return frame
+}
+
+// forecastSeries returns a wide time series of the actual minutes from the
+// start of the period until now, and of every forecast from now until the
+// end of the period.
+func forecastSeries(status client.BuildAccountResponse, samples []sampler.Sample, forecasts []minutesForecast, now time.Time) *data.Frame {
+	minutes := status.Minutes
+	actual := func(v int64) []*float64 {
+		value := float64(v)
+		row := []*float64{&value}
+		for range forecasts {
+			row = append(row, nil)
+		}
+		return row
+	}
+
+	frame := data.NewFrame("build_minutes_timeseries",
+		data.NewField("Time", nil, []time.Time{}),
+		data.NewField("Actual", nil, []*float64{}),
+	)
+	for _, forecast := range forecasts {
+		frame.Fields = append(frame.Fields, data.NewField("Forecast", data.Labels{"method": forecast.method}, []*float64{}))
+	}
+	frame.Meta = &data.FrameMeta{Type: data.FrameTypeTimeSeriesWide}
+
+	appendRow := func(at time.Time, values []*float64) {
+		row := []any{at}
+		for _, value := range values {
+			row = append(row, value)
+		}
+		frame.AppendRow(row...)
+	}
+
+	if !minutes.PeriodStartDate.IsZero() {
+		appendRow(minutes.PeriodStartDate, actual(0))
+	}
+	for _, sample := range samples {
+		appendRow(sample.Time, actual(sample.MinutesCurrent))
+	}
+
+	// the forecasts start from the current minutes so the lines connect
+	current := actual(minutes.Current)
+	for i := range forecasts {
+		current[i+1] = current[0]
+	}
+	appendRow(now, current)
+
+	if minutes.PeriodEndDate.After(now) {
+		end := []*float64{nil}
+		for _, forecast := range forecasts {
+			projected := forecast.projected
+			end = append(end, &projected)
+		}
+		appendRow(minutes.PeriodEndDate, end)
+	}
+
+	return frame
+}
@@ -0,0 +1,83 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/sampler"
+)
+
+func TestForecastMinutes(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	status := client.BuildAccountResponse{}
+	status.Minutes.Current = 100
+	status.Minutes.IncludedMinutesWithPacks = 300
+	status.Minutes.PeriodStartDate = day(1)
+	status.Minutes.PeriodEndDate = day(31)
+	now := day(11)
+
+	t.Run("linear rate of the period", func(t *testing.T) {
+		forecasts := forecastMinutes(status, nil, now)
+		require.Len(t, forecasts, 1)
+
+		forecast := forecasts[0]
+		assert.Equal(t, forecastLinear, forecast.method)
+		assert.InDelta(t, 300, forecast.projected, 0.001)
+		assert.Zero(t, forecast.overage)
+		assert.Nil(t, forecast.exhaustion)
+	})
+
+	t.Run("recent rate of the samples", func(t *testing.T) {
+		samples := []sampler.Sample{
+			{Time: day(9), MinutesCurrent: 10},
+			{Time: now.Add(-12 * time.Hour), MinutesCurrent: 70},
+			{Time: now, MinutesCurrent: 100},
+		}
+
+		forecasts := forecastMinutes(status, samples, now)
+		require.Len(t, forecasts, 2)
+
+		forecast := forecasts[1]
+		assert.Equal(t, forecastRecent, forecast.method)
+		assert.InDelta(t, 60.0/24, forecast.rate, 0.001)
+		assert.InDelta(t, 100+60*20, forecast.projected, 0.001)
+		assert.InDelta(t, 1000, forecast.overage, 0.001)
+		require.NotNil(t, forecast.exhaustion)
+		assert.Equal(t, day(14).Add(8*time.Hour), *forecast.exhaustion)
+	})
+
+	t.Run("exhausted quota", func(t *testing.T) {
+		exhausted := status
+		exhausted.Minutes.Current = 400
+
+		forecasts := forecastMinutes(exhausted, nil, now)
+		require.Len(t, forecasts, 1)
+		require.NotNil(t, forecasts[0].exhaustion)
+		assert.Equal(t, now, *forecasts[0].exhaustion)
+		assert.InDelta(t, 900, forecasts[0].overage, 0.001)
+	})
+
+	t.Run("series of actuals and forecasts", func(t *testing.T) {
+		samples := []sampler.Sample{{Time: day(5), MinutesCurrent: 40}}
+		frame := forecastSeries(status, samples, forecastMinutes(status, samples, now), now)
+
+		assert.Equal(t, data.FrameTypeTimeSeriesWide, frame.Meta.Type)
+		require.Len(t, frame.Fields, 3)
+		assert.Equal(t, []time.Time{day(1), day(5), now, day(31)}, fieldValues[time.Time](frame.Fields[0]))
+		assert.Equal(t, data.Labels{"method": forecastLinear}, frame.Fields[2].Labels)
+
+		actual := fieldValues[*float64](frame.Fields[1])
+		assert.Equal(t, 40.0, *actual[1])
+		assert.Nil(t, actual[3])
+
+		forecast := fieldValues[*float64](frame.Fields[2])
+		assert.Nil(t, forecast[0])
+		assert.Equal(t, 100.0, *forecast[2])
+		assert.InDelta(t, 300, *forecast[3], 0.001)
+	})
+}
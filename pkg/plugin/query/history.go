@@ -0,0 +1,67 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+// deploysDoer returns the deploys of a site, backed by the history store
+// when one is configured.
+func (q QueryHandler) deploysDoer(qm queryModel) client.Doer[client.DeploysResponse] {
+	if q.history == nil {
+		return q.client.GetDeployments
+	}
+
+	return withHistory(q.client.GetDeployments, q.resolveSiteId, q.history.Deploys, q.history.AddDeploys, client.Deploy.Created, qm.TimeRange.From)
+}
+
+// buildsDoer returns the builds of a site, backed by the history store when
+// one is configured.
+func (q QueryHandler) buildsDoer(qm queryModel) client.Doer[client.BuildsResponse] {
+	if q.history == nil {
+		return q.client.GetBuilds
+	}
+
+	return withHistory(q.client.GetBuilds, q.resolveSiteId, q.history.Builds, q.history.AddBuilds, client.Build.Created, qm.TimeRange.From)
+}
+
+// withHistory wraps a doer so every row Netlify returns is ingested into the
+// history store, and stored rows older than the oldest row Netlify returned
+// fill time ranges reaching further back than Netlify lists. History
+// failures are logged and never fail the query.
+func withHistory[T ~[]E, E any](doer client.Doer[T], resolve func(string) string, load func(string, time.Time, time.Time) (T, error), add func(string, T) error, createdAt func(E) time.Time, from time.Time) client.Doer[T] {
+	return func(ctx context.Context, siteId string) (T, client.Meta, error) {
+		rows, meta, err := doer(ctx, siteId)
+		if err != nil {
+			return rows, meta, err
+		}
+
+		siteId = resolve(siteId)
+		if err := add(siteId, rows); err != nil {
+			backend.Logger.Warn("failed to store history", "siteId", siteId, "err", err.Error())
+		}
+
+		oldest := time.Now()
+		for _, row := range rows {
+			if created := createdAt(row); created.Before(oldest) {
+				oldest = created
+			}
+		}
+
+		if !from.IsZero() && !from.Before(oldest) {
+			return rows, meta, nil
+		}
+
+		older, err := load(siteId, from, oldest)
+		if err != nil {
+			backend.Logger.Warn("failed to load history", "siteId", siteId, "err", err.Error())
+			return rows, meta, nil
+		}
+
+		return append(rows, older...), meta, nil
+	}
+}
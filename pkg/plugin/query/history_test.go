@@ -0,0 +1,53 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/history"
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+func TestHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":"deploy-3","state":"ready","created_at":"2024-01-03T00:00:00Z"},{"id":"deploy-2","state":"ready","created_at":"2024-01-02T00:00:00Z"}]`)
+	}))
+	defer server.Close()
+
+	store, err := history.Open(t.TempDir())
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.AddDeploys("site-a", client.DeploysResponse{
+		{ID: "deploy-2", State: "building", CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{ID: "deploy-1", State: "ready", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: "deploy-0", State: "ready", CreatedAt: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)},
+	}))
+
+	handler := NewQueryHandler(newTestClient(t, models.Settings{BaseUrl: server.URL, SiteId: "site-a"}), nil, store, nil)
+
+	res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+		JSON:      []byte(`{"entity":"deployments"}`),
+		TimeRange: backend.TimeRange{From: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)},
+	})
+	require.NoError(t, res.Error)
+	require.Len(t, res.Frames, 1)
+
+	field, _ := res.Frames[0].FieldByName("ID")
+	require.NotNil(t, field)
+	assert.Equal(t, []string{"deploy-3", "deploy-2", "deploy-1"}, []string{*field.At(0).(*string), *field.At(1).(*string), *field.At(2).(*string)})
+
+	deploys, err := store.Deploys("site-a", time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, deploys, 4)
+	assert.Equal(t, "deploy-3", deploys[0].ID)
+	assert.Equal(t, "ready", deploys[1].State)
+}
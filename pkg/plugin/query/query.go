@@ -4,21 +4,35 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/grafana-plugin-sdk-go/data/framestruct"
+	"github.com/grafana/grafana-plugin-sdk-go/live"
 
 	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/events"
+	"github.com/grafana/netlify-datasource/pkg/plugin/history"
+	"github.com/grafana/netlify-datasource/pkg/plugin/sampler"
+	"github.com/grafana/netlify-datasource/pkg/plugin/stream"
 )
 
 type QueryHandler struct {
-	client client.Client
+	client  client.Client
+	events  *events.Store
+	history *history.Store
+	samples *sampler.Sampler
 }
 
-func NewQueryHandler(client client.Client) QueryHandler {
+func NewQueryHandler(client client.Client, events *events.Store, history *history.Store, samples *sampler.Sampler) QueryHandler {
 	return QueryHandler{
-		client: client,
+		client:  client,
+		events:  events,
+		history: history,
+		samples: samples,
 	}
 }
 
@@ -44,8 +58,50 @@ type queryModel struct {
 	ParsingOptions struct {
 		SelectedFields []string `json:"selectedFields"`
 	} `json:"parsingOptions"`
+	// ErrorMode decides how multi-site queries handle failing sites, see
+	// errorModeStrict and errorModePartial.
+	ErrorMode string `json:"errorMode"`
+	// Filters keep the rows matching every condition.
+	Filters []filter `json:"filters"`
+	// OrderBy sorts the rows merged from every site by a field, in the
+	// Direction "asc" (the default) or "desc".
+	OrderBy   string `json:"orderBy"`
+	Direction string `json:"direction"`
+	// Offset skips the first rows and Limit caps the rows returned, after
+	// sorting.
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+	// Aggregate returns time series of the rows created per Interval
+	// instead of a table.
+	Aggregate *aggregation `json:"aggregate"`
+	// Live streams the deploys of a site that change after the query ran.
+	Live bool `json:"live"`
+	// Series returns the sampled build status over the time range instead
+	// of the current one.
+	Series bool `json:"series"`
+	// TimeRange is the time range of the backend.DataQuery.
+	TimeRange backend.TimeRange `json:"-"`
+	// Interval is the interval of the backend.DataQuery, widened so time
+	// series stay within its MaxDataPoints.
+	Interval time.Duration `json:"-"`
+
+	// redactions are applied before anything else, so filters and order
+	// cannot reveal redacted values.
+	redactions []fieldRef
+	rowFilters []rowFilter
+	rowOrder   *rowOrder
+	// channel is the live channel the deploys stream on.
+	channel string
 }
 
+const (
+	// errorModePartial returns the rows of the sites that succeeded and a
+	// warning per failed site. It is the default.
+	errorModePartial = "partial"
+	// errorModeStrict fails the whole query as soon as one site fails.
+	errorModeStrict = "strict"
+)
+
 func parseSiteIdsAsVariables(siteIds string) ([]string, error) {
 	siteIdSlice := make([]string, 0)
 	// expecting string as "siteId" or "{siteId, siteId, siteId}"
@@ -62,186 +118,269 @@ func parseSiteIdsAsVariables(siteIds string) ([]string, error) {
 }
 
 func (q QueryHandler) Query(ctx context.Context, pCtx backend.PluginContext, query backend.DataQuery) backend.DataResponse {
+	if q.client.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(q.client.QueryTimeout)*time.Second)
+		defer cancel()
+	}
+
 	// Unmarshal the JSON into our queryModel.
 	var qm queryModel
 	err := json.Unmarshal(query.JSON, &qm)
 	if err != nil {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("json unmarshal failed on query: %v", err.Error()))
+		return badRequest("json unmarshal failed on query: %v", err.Error())
 	}
 
 	backend.Logger.Info("queryParams", "SelectedFields", qm.ParsingOptions.SelectedFields)
 
-	selectedFields := qm.ParsingOptions.SelectedFields
+	qm.TimeRange = query.TimeRange
+	qm.Interval = bucketInterval(query.TimeRange.From, query.TimeRange.To, query.Interval, query.MaxDataPoints)
+
+	if qm.ErrorMode == "" {
+		qm.ErrorMode = errorModePartial
+	}
+
+	if qm.ErrorMode != errorModePartial && qm.ErrorMode != errorModeStrict {
+		return badRequest("unknown errorMode %q, expected %q or %q", qm.ErrorMode, errorModePartial, errorModeStrict)
+	}
+
+	// series are made of samples, their fields differ from the rows
+	if qm.Series {
+		qm.ParsingOptions.SelectedFields, err = resolveSeriesFields(qm.Entity, qm.ParsingOptions.SelectedFields)
+	} else {
+		qm.ParsingOptions.SelectedFields, err = resolveFields(qm.Entity, qm.ParsingOptions.SelectedFields)
+	}
+	if err != nil {
+		return badRequest("invalid selectedFields: %v", err)
+	}
+
+	qm.redactions = compileRedactions(qm.Entity, q.client.Settings)
+
+	qm.rowFilters, err = compileFilters(qm.Entity, qm.Filters)
+	if err != nil {
+		return badRequest("invalid filters: %v", err)
+	}
+
+	qm.rowOrder, err = compileOrder(qm.Entity, qm.OrderBy, qm.Direction)
+	if err != nil {
+		return badRequest("invalid orderBy: %v", err)
+	}
+
+	if qm.Offset < 0 || qm.Limit < 0 {
+		return badRequest("offset and limit must not be negative")
+	}
+
+	err = compileAggregation(qm.Entity, qm.Aggregate)
+	if err != nil {
+		return badRequest("invalid aggregate: %v", err)
+	}
+
+	// the counts are of every row, paging them would skew the series
+	if qm.Aggregate != nil && (qm.Offset > 0 || qm.Limit > 0) {
+		return badRequest("offset and limit cannot be combined with aggregate")
+	}
 
 	sitesIds, err := parseSiteIdsAsVariables(qm.SiteId)
 	if err != nil {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed on parsing siteIds: %v", err.Error()))
+		return badRequest("failed on parsing siteIds: %v", err.Error())
 	}
 
 	backend.Logger.Info("query", "entity", qm.Entity, "siteId", qm.SiteId, "sitesIds", sitesIds)
 
+	if qm.Live {
+		if qm.Entity != "deployments" || len(sitesIds) != 1 || qm.Aggregate != nil {
+			return badRequest("live is only available for deployments of a single site")
+		}
+
+		if pCtx.DataSourceInstanceSettings == nil {
+			return badRequest("live needs the data source of the query")
+		}
+
+		siteId := sitesIds[0]
+		if siteId == "" {
+			siteId = q.client.SiteId
+		}
+
+		optionsKey, err := registerStreamOptions(qm)
+		if err != nil {
+			return badRequest("invalid live query: %v", err)
+		}
+
+		qm.channel = live.Channel{
+			Scope:     live.ScopeDatasource,
+			Namespace: pCtx.DataSourceInstanceSettings.UID,
+			Path:      stream.DeploysPath(siteId, optionsKey),
+		}.String()
+	}
+
+	if qm.Series && (qm.Entity != "builds-account" || len(qm.rowFilters) > 0 || qm.rowOrder != nil) {
+		return badRequest("series are only available for builds-account, without filters or orderBy")
+	}
+
 	switch qm.Entity {
 	case "builds":
-		return q.HandleBuildsQuery(ctx, sitesIds, selectedFields)
+		return q.HandleBuildsQuery(ctx, qm, sitesIds)
 	case "deployments":
-		return q.HandleDeploymentsQuery(ctx, sitesIds, selectedFields)
+		return q.HandleDeploymentsQuery(ctx, qm, sitesIds)
 	case "forms":
-		return q.HandleFormsQuery(ctx, sitesIds)
+		return q.HandleFormsQuery(ctx, qm, sitesIds)
 	case "form-submissions":
-		return q.HandleFormSubmissionsQuery(ctx, sitesIds)
+		return q.HandleFormSubmissionsQuery(ctx, qm, sitesIds)
 	case "builds-account":
-		return q.HandleBuildAccountDetails(ctx)
+		return q.HandleBuildAccountDetails(ctx, qm)
 	case "sites":
-		return q.HandleSitesQuery(ctx)
+		return q.HandleSitesQuery(ctx, qm)
 	case "accounts":
-		return q.HandleAccounts(ctx)
+		return q.HandleAccounts(ctx, qm)
+	case "dora":
+		return q.HandleDoraQuery(ctx, qm, sitesIds)
+	case "deploy-annotations":
+		return q.HandleDeployAnnotationsQuery(ctx, qm, sitesIds)
+	case "build-minutes-forecast":
+		return q.HandleBuildMinutesForecastQuery(ctx, qm)
+	case "build-minutes-by-site":
+		return q.HandleBuildMinutesBySiteQuery(ctx, qm, sitesIds)
 	case "":
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("missing query param entity: %v", err.Error()))
+		return badRequest("missing query param entity")
 	default:
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("Unidentified query param entity: %v", err.Error()))
-	}
-}
-
-func contains(slice []string, item string) bool {
-	for _, value := range slice {
-		if value == item {
-			return true
-		}
+		return badRequest("Unidentified query param entity: %q", qm.Entity)
 	}
-	return false
 }
 
-func (q QueryHandler) HandleBuildsQuery(ctx context.Context, siteIds []string, selectedFields []string) backend.DataResponse {
+func (q QueryHandler) HandleBuildsQuery(ctx context.Context, qm queryModel, siteIds []string) backend.DataResponse {
 	var response backend.DataResponse
 
-	res, errors := client.DoGets[client.BuildsResponse](ctx, q.client.GetBuilds, siteIds)
-	if len(errors) > 0 {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed to get builds: %v", errors[0].Error()))
-	}
-
-	builds := make(client.BuildsResponse, 0, len(res))
-	for _, r := range res {
-		builds = append(builds, r...)
+	ctx = client.WithSince(ctx, qm.TimeRange.From)
+	results := client.DoGets[client.BuildsResponse](ctx, q.buildsDoer(qm), siteIds, q.client.Concurrency)
+	notices, err := siteNotices(results, qm.ErrorMode)
+	if err != nil {
+		return errorResponse(err, "failed to get builds")
 	}
 
-	// ID Sha CreatedAt State
-	// if len(selectedFields) > 0 {
-	// 	for _, build := range builds {
-	// 		buildValue := reflect.ValueOf(build).Elem()
-
-	// 		// for _, v := range selectedFields {
-	// 		// 	fieldValue := buildValue.FieldByName(v)
-	// 		// 	if !fieldValue.IsValid() {
-	// 		// 		continue
-	// 		// 	}
+	builds := inTimeRange(client.Flatten(results), qm.TimeRange, client.Build.Created)
+	builds = shapeRows(builds, qm)
+	meta := results.Meta()
 
-	// 		// 	fieldValue.Set(reflect.Zero(fieldValue.Type()))
-	// 		// }
-
-	// 		for i := 0; i < buildValue.NumField(); i++ {
-	// 			fieldName := buildValue.Type().Field(i)
-
-	// 			// Check if the field should be kept
-	// 			if !contains(selectedFields, fieldName.Name) {
-	// 				// Reset the field's value to its zero value
-	// 				fieldValue := buildValue.Field(i)
-	// 				fieldValue.Set(reflect.Zero(fieldValue.Type()))
-	// 			}
-	// 		}
-	// 	}
-	// }
+	if qm.Aggregate != nil {
+		return timeSeriesResponse(aggregate("builds", builds, qm.Aggregate, qm.TimeRange, qm.Interval, client.Build.Created), meta, notices)
+	}
 
-	backend.Logger.Info("HandleBuildsQuery", "len", len(res), "builds", builds)
+	backend.Logger.Info("HandleBuildsQuery", "len", len(results), "builds", builds)
 
 	dataFrames, err := framestruct.ToDataFrame("builds", builds)
 	if err != nil {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed Builds to frame conversion: %v", err.Error()))
+		return conversionError(err, "failed Builds to frame conversion")
 	}
 
-	// dataFrames.
+	selectFields(dataFrames, qm.ParsingOptions.SelectedFields)
+	applyMeta(dataFrames, meta)
+	dataFrames.AppendNotices(notices...)
 
 	response.Frames = append(response.Frames, dataFrames)
 
 	return response
 }
 
-func (q QueryHandler) HandleDeploymentsQuery(ctx context.Context, siteIds []string, selectedFields []string) backend.DataResponse {
+func (q QueryHandler) HandleDeploymentsQuery(ctx context.Context, qm queryModel, siteIds []string) backend.DataResponse {
 	var response backend.DataResponse
 
-	res, errors := client.DoGets[client.DeploysResponse](ctx, q.client.GetDeployments, siteIds)
-	if len(errors) > 0 {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed to get deployments: %v", errors[0].Error()))
+	ctx = client.WithSince(ctx, qm.TimeRange.From)
+	results := client.DoGets[client.DeploysResponse](ctx, q.deploysDoer(qm), siteIds, q.client.Concurrency)
+	notices, err := siteNotices(results, qm.ErrorMode)
+	if err != nil {
+		return errorResponse(err, "failed to get deployments")
 	}
 
-	deployments := make(client.DeploysResponse, 0, len(res))
-	for _, r := range res {
-		deployments = append(deployments, r...)
+	deployments := inTimeRange(client.Flatten(results), qm.TimeRange, client.Deploy.Created)
+	deployments = shapeRows(deployments, qm)
+	meta := results.Meta()
+
+	if qm.Aggregate != nil {
+		return timeSeriesResponse(aggregate("deployments", deployments, qm.Aggregate, qm.TimeRange, qm.Interval, client.Deploy.Created), meta, notices)
 	}
 
 	dataFrames, err := framestruct.ToDataFrame("deployments", deployments)
 	if err != nil {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed deployments to frame conversion: %v", err.Error()))
+		return conversionError(err, "failed deployments to frame conversion")
 	}
 
+	selectFields(dataFrames, qm.ParsingOptions.SelectedFields)
+	applyMeta(dataFrames, meta)
+	dataFrames.Meta.Channel = qm.channel
+	dataFrames.AppendNotices(notices...)
+
 	response.Frames = append(response.Frames, dataFrames)
 
 	return response
 }
 
-func (q QueryHandler) HandleSitesQuery(ctx context.Context) backend.DataResponse {
+func (q QueryHandler) HandleSitesQuery(ctx context.Context, qm queryModel) backend.DataResponse {
 	var response backend.DataResponse
 
-	res, err := q.client.GetSites()
+	res, meta, err := q.client.GetSites(ctx)
 	if err != nil {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed to get deploys: %v", err.Error()))
+		return errorResponse(err, "failed to get sites")
 	}
 
+	res = shapeRows(res, qm)
+
 	dataFrames, err := framestruct.ToDataFrame("sites", res)
 	if err != nil {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed Sites to frame conversion: %v", err.Error()))
+		return conversionError(err, "failed Sites to frame conversion")
 	}
 
+	selectFields(dataFrames, qm.ParsingOptions.SelectedFields)
+	applyMeta(dataFrames, meta)
+
 	// add the frames to the response.
 	response.Frames = append(response.Frames, dataFrames)
 
 	return response
 }
 
-func (q QueryHandler) HandleFormsQuery(ctx context.Context, siteIds []string) backend.DataResponse {
+func (q QueryHandler) HandleFormsQuery(ctx context.Context, qm queryModel, siteIds []string) backend.DataResponse {
 	var response = backend.DataResponse{}
 
-	res, errors := client.DoGets[client.FormsResponse](ctx, q.client.GetForms, siteIds)
-	if len(errors) > 0 {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed to get forms: %v", errors[0].Error()))
+	results := client.DoGets[client.FormsResponse](ctx, q.client.GetForms, siteIds, q.client.Concurrency)
+	notices, err := siteNotices(results, qm.ErrorMode)
+	if err != nil {
+		return errorResponse(err, "failed to get forms")
 	}
 
-	forms := make(client.FormsResponse, 0, len(res))
-	for _, r := range res {
-		forms = append(forms, r...)
-	}
+	forms := shapeRows(client.Flatten(results), qm)
+	meta := results.Meta()
 
 	dataFrames, err := framestruct.ToDataFrame("forms", forms)
 	if err != nil {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed forms to frame conversion: %v", err.Error()))
+		return conversionError(err, "failed forms to frame conversion")
 	}
 
+	selectFields(dataFrames, qm.ParsingOptions.SelectedFields)
+	applyMeta(dataFrames, meta)
+	dataFrames.AppendNotices(notices...)
+
 	// add the frames to the response.
 	response.Frames = append(response.Frames, dataFrames)
 
 	return response
 }
 
-func (q QueryHandler) HandleFormSubmissionsQuery(ctx context.Context, siteIds []string) backend.DataResponse {
+func (q QueryHandler) HandleFormSubmissionsQuery(ctx context.Context, qm queryModel, siteIds []string) backend.DataResponse {
 	var response = backend.DataResponse{}
 
-	res, errors := client.DoGets[client.FormSubmissionsResponse](ctx, q.client.GetFormSubmittions, siteIds)
-	if len(errors) > 0 {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed to get forms submissions: %v", errors[0].Error()))
+	ctx = client.WithSince(ctx, qm.TimeRange.From)
+	results := client.DoGets[client.FormSubmissionsResponse](ctx, q.client.GetFormSubmittions, siteIds, q.client.Concurrency)
+	notices, err := siteNotices(results, qm.ErrorMode)
+	if err != nil {
+		return errorResponse(err, "failed to get forms submissions")
 	}
 
-	form_submissions := make(client.FormSubmissionsResponse, 0, len(res))
-	for _, r := range res {
-		form_submissions = append(form_submissions, r...)
+	form_submissions := inTimeRange(client.Flatten(results), qm.TimeRange, client.FormSubmission.Created)
+	form_submissions = shapeRows(form_submissions, qm)
+	meta := results.Meta()
+
+	if qm.Aggregate != nil {
+		return timeSeriesResponse(aggregate("form_submissions", form_submissions, qm.Aggregate, qm.TimeRange, qm.Interval, client.FormSubmission.Created), meta, notices)
 	}
 
 	// create data frame response.
@@ -249,46 +388,178 @@ func (q QueryHandler) HandleFormSubmissionsQuery(ctx context.Context, siteIds []
 	// https://grafana.com/developers/plugin-tools/introduction/data-frames
 	dataFrames, err := framestruct.ToDataFrame("form_submissions", form_submissions)
 	if err != nil {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed forms submissions to frame conversion: %v", err.Error()))
+		return conversionError(err, "failed forms submissions to frame conversion")
 	}
 
+	selectFields(dataFrames, qm.ParsingOptions.SelectedFields)
+	applyMeta(dataFrames, meta)
+	dataFrames.AppendNotices(notices...)
+
 	response.Frames = append(response.Frames, dataFrames)
 
 	return response
 }
 
-func (q QueryHandler) HandleBuildAccountDetails(ctx context.Context) backend.DataResponse {
+func (q QueryHandler) HandleBuildAccountDetails(ctx context.Context, qm queryModel) backend.DataResponse {
 	var response backend.DataResponse
 
-	res, err := q.client.GetBuildAccountDetails()
+	if qm.Series {
+		return q.HandleBuildAccountSamples(qm)
+	}
+
+	res, meta, err := q.client.GetBuildAccountDetails(ctx)
 	if err != nil {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed to get build account details: %v", err.Error()))
+		return errorResponse(err, "failed to get build account details")
+	}
+
+	// the build status is a single row, filters can still drop it
+	rows := shapeRows([]client.BuildAccountResponse{res}, qm)
+
+	dataFrames, err := framestruct.ToDataFrame("build_account_details", rows)
+	if err != nil {
+		return conversionError(err, "failed Build Account to frame conversion")
+	}
+
+	selectFields(dataFrames, qm.ParsingOptions.SelectedFields)
+	applyMeta(dataFrames, meta)
+
+	response.Frames = append(response.Frames, dataFrames)
+
+	return response
+}
+
+// HandleBuildAccountSamples returns the build status sampled over the time
+// range as a wide time series.
+func (q QueryHandler) HandleBuildAccountSamples(qm queryModel) backend.DataResponse {
+	var response backend.DataResponse
+
+	if q.samples == nil {
+		return badRequest("build status sampling is disabled, it needs an account id and a positive sample interval")
 	}
 
-	dataFrames, err := framestruct.ToDataFrame("build_account_details", res)
+	samples := redactRows(q.samples.Samples(qm.TimeRange.From, qm.TimeRange.To), sampleRedactions(q.client.Settings))
+
+	dataFrames, err := framestruct.ToDataFrame("build_account_samples", samples)
 	if err != nil {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed Build Account to frame conversion: %v", err.Error()))
+		return conversionError(err, "failed Build Account samples to frame conversion")
+	}
+
+	// the series keeps its time field whatever is selected
+	if selected := qm.ParsingOptions.SelectedFields; len(selected) > 0 && !slices.Contains(selected, "Time") {
+		qm.ParsingOptions.SelectedFields = append([]string{"Time"}, selected...)
 	}
 
+	selectFields(dataFrames, qm.ParsingOptions.SelectedFields)
+	dataFrames.Meta = &data.FrameMeta{Type: data.FrameTypeTimeSeriesWide}
+
 	response.Frames = append(response.Frames, dataFrames)
 
 	return response
 }
 
-func (q QueryHandler) HandleAccounts(ctx context.Context) backend.DataResponse {
+func (q QueryHandler) HandleAccounts(ctx context.Context, qm queryModel) backend.DataResponse {
 	var response backend.DataResponse
 
-	res, err := q.client.GetAccounts()
+	res, meta, err := q.client.GetAccounts(ctx)
 	if err != nil {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed to get accounts: %v", err.Error()))
+		return errorResponse(err, "failed to get accounts")
 	}
 
+	res = shapeRows(res, qm)
+
 	dataFrames, err := framestruct.ToDataFrame("accounts", res)
 	if err != nil {
-		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("failed Build Account to frame conversion: %v", err.Error()))
+		return conversionError(err, "failed Accounts to frame conversion")
 	}
 
+	selectFields(dataFrames, qm.ParsingOptions.SelectedFields)
+	applyMeta(dataFrames, meta)
+
 	response.Frames = append(response.Frames, dataFrames)
 
 	return response
 }
+
+// shapeRows applies the redactions, filters, order, offset and limit of a
+// query to the rows merged from every site.
+func shapeRows[T ~[]E, E any](rows T, qm queryModel) T {
+	rows = redactRows(rows, qm.redactions)
+	rows = filterRows(rows, qm.rowFilters)
+	rows = sortRows(rows, qm.rowOrder)
+
+	return pageRows(rows, qm.Offset, qm.Limit)
+}
+
+// timeSeriesResponse is the response of an aggregated query, every series
+// gets the cache meta and only the first one the notices.
+func timeSeriesResponse(frames data.Frames, meta client.Meta, notices []data.Notice) backend.DataResponse {
+	applyMeta(frames[0], meta)
+	frames[0].AppendNotices(notices...)
+
+	meta.Truncated = false
+	for _, frame := range frames[1:] {
+		applyMeta(frame, meta)
+	}
+
+	return backend.DataResponse{Frames: frames}
+}
+
+// cacheMeta is the custom frame metadata telling whether the data of a frame
+// was served from the cache and how old it is.
+type cacheMeta struct {
+	Cached   bool    `json:"cached"`
+	CacheAge float64 `json:"cacheAgeSeconds"`
+}
+
+// applyMeta records on the frame how its response was assembled: whether it
+// came from the cache, and a warning when pagination stopped at the
+// configured maxPages.
+func applyMeta(frame *data.Frame, meta client.Meta) {
+	custom := cacheMeta{Cached: meta.Cached}
+	if !meta.CachedAt.IsZero() {
+		custom.CacheAge = time.Since(meta.CachedAt).Seconds()
+	}
+
+	if frame.Meta == nil {
+		frame.Meta = &data.FrameMeta{}
+	}
+	frame.Meta.Custom = custom
+
+	if !meta.Truncated {
+		return
+	}
+
+	frame.AppendNotices(data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text:     fmt.Sprintf("Results were capped after %d pages, increase Max pages in the data source settings to load more.", meta.Pages),
+	})
+}
+
+// siteNotices checks the per-site results of a multi-site query. In strict
+// mode the first failed site fails the query. In partial mode every failed
+// site becomes a warning notice, and the query only fails when no site
+// succeeded.
+func siteNotices[T any](results client.Results[T], errorMode string) ([]data.Notice, error) {
+	errors := results.Errors()
+	if len(errors) == 0 {
+		return nil, nil
+	}
+
+	if errorMode == errorModeStrict || len(errors) == len(results) {
+		return nil, errors[0]
+	}
+
+	notices := make([]data.Notice, 0, len(errors))
+	for _, result := range results {
+		if result.Err == nil {
+			continue
+		}
+
+		notices = append(notices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Site %s failed and is missing from the results: %s", result.Variable, result.Err.Error()),
+		})
+	}
+
+	return notices, nil
+}
@@ -0,0 +1,110 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+	"github.com/grafana/netlify-datasource/pkg/plugin/sampler"
+)
+
+func TestSiteNotices(t *testing.T) {
+	results := client.Results[client.DeploysResponse]{
+		{Variable: "site-a", Value: client.DeploysResponse{{ID: "deploy-a"}}},
+		{Variable: "site-b", Err: errors.New("not found")},
+	}
+
+	t.Run("partial mode turns failed sites into warnings", func(t *testing.T) {
+		notices, err := siteNotices(results, errorModePartial)
+		require.NoError(t, err)
+		require.Len(t, notices, 1)
+		assert.Contains(t, notices[0].Text, "site-b")
+		assert.Contains(t, notices[0].Text, "not found")
+	})
+
+	t.Run("strict mode fails on the first failed site", func(t *testing.T) {
+		_, err := siteNotices(results, errorModeStrict)
+		assert.EqualError(t, err, "not found")
+	})
+
+	t.Run("partial mode fails when every site failed", func(t *testing.T) {
+		_, err := siteNotices(results[1:], errorModePartial)
+		assert.EqualError(t, err, "not found")
+	})
+}
+
+func TestBuildAccountSamples(t *testing.T) {
+	fetch := func(ctx context.Context) (client.BuildAccountResponse, client.Meta, error) {
+		return client.BuildAccountResponse{Active: 2, Enqueued: 3}, client.Meta{}, nil
+	}
+
+	samples := sampler.Start(fetch, time.Hour, 10)
+	defer samples.Close()
+	require.Eventually(t, func() bool { return len(samples.Samples(time.Time{}, time.Time{})) == 1 }, time.Second, time.Millisecond)
+
+	handler := NewQueryHandler(newTestClient(t, models.Settings{}), nil, nil, samples)
+	timeRange := backend.TimeRange{From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Minute)}
+
+	t.Run("returns the samples as a time series", func(t *testing.T) {
+		res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON:      []byte(`{"entity":"builds-account","series":true}`),
+			TimeRange: timeRange,
+		})
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 1)
+		assert.Equal(t, data.FrameTypeTimeSeriesWide, res.Frames[0].Meta.Type)
+
+		field, _ := res.Frames[0].FieldByName("Enqueued")
+		require.NotNil(t, field)
+		require.Equal(t, 1, field.Len())
+		assert.Equal(t, int64(3), *field.At(0).(*int64))
+	})
+
+	t.Run("selects the fields of the samples", func(t *testing.T) {
+		res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON:      []byte(`{"entity":"builds-account","series":true,"parsingOptions":{"selectedFields":["enqueued","MinutesCurrent"]}}`),
+			TimeRange: timeRange,
+		})
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 1)
+
+		names := []string{}
+		for _, field := range res.Frames[0].Fields {
+			names = append(names, field.Name)
+		}
+		assert.Equal(t, []string{"Time", "Enqueued", "MinutesCurrent"}, names)
+	})
+
+	t.Run("rejects fields the samples do not have", func(t *testing.T) {
+		res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON:      []byte(`{"entity":"builds-account","series":true,"parsingOptions":{"selectedFields":["Minutes.PeriodEndDate"]}}`),
+			TimeRange: timeRange,
+		})
+		assert.Equal(t, backend.StatusBadRequest, res.Status)
+		assert.Contains(t, res.Error.Error(), `unknown field "Minutes.PeriodEndDate"`)
+	})
+
+	t.Run("rejects series without sampling", func(t *testing.T) {
+		res := NewQueryHandler(newTestClient(t, models.Settings{}), nil, nil, nil).Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON:      []byte(`{"entity":"builds-account","series":true}`),
+			TimeRange: timeRange,
+		})
+		assert.Equal(t, backend.StatusBadRequest, res.Status)
+		assert.Contains(t, res.Error.Error(), "sampling is disabled")
+	})
+
+	t.Run("rejects series of other entities", func(t *testing.T) {
+		res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON: []byte(`{"entity":"deployments","series":true}`),
+		})
+		assert.Equal(t, backend.StatusBadRequest, res.Status)
+	})
+}
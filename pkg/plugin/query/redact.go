@@ -0,0 +1,221 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+// redactedValue replaces the text of redacted fields.
+const redactedValue = "[redacted]"
+
+// sensitiveFields are redacted from the rows of an entity unless redaction
+// is disabled in the settings. A struct field redacts every field below it.
+var sensitiveFields = map[string][]string{
+	"sites": {"Password", "DeployHook", "DefaultHooksData.AccessToken", "BuildSettings.Env"},
+}
+
+// compileRedactions returns the fields of an entity redacted by default and
+// by the settings. Configured names the entity does not have are ignored, as
+// the list applies to every entity.
+func compileRedactions(entity string, settings models.Settings) []fieldRef {
+	s, ok := entitySchemas[entity]
+	if !ok || settings.DisableRedaction {
+		return nil
+	}
+
+	redactions := []fieldRef{}
+	for _, name := range append(slices.Clone(sensitiveFields[entity]), settings.RedactFields...) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		for _, col := range s {
+			if coversColumn(name, col) {
+				redactions = append(redactions, fieldRef{column: col})
+			}
+		}
+
+		if ref, ok := s.lookup(name); ok && ref.key != "" {
+			redactions = append(redactions, ref)
+		}
+	}
+
+	return redactions
+}
+
+// coversColumn reports whether name is the column or a struct above it.
+func coversColumn(name string, col column) bool {
+	for _, colName := range []string{col.name, col.jsonName} {
+		if strings.EqualFold(name, colName) {
+			return true
+		}
+
+		prefix := name + "."
+		if len(colName) > len(prefix) && strings.EqualFold(colName[:len(prefix)], prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactRows returns a copy of the rows with the values of the redacted
+// fields replaced, the rows themselves may be shared with the cache. Text
+// becomes redactedValue and other values their zero value, unset values are
+// kept.
+func redactRows[T ~[]E, E any](rows T, redactions []fieldRef) T {
+	if len(redactions) == 0 {
+		return rows
+	}
+
+	redacted := slices.Clone(rows)
+	for i := range redacted {
+		row := reflect.ValueOf(&redacted[i]).Elem()
+		for _, ref := range redactions {
+			redactValue(row.FieldByIndex(ref.index), ref.key)
+		}
+	}
+
+	return redacted
+}
+
+// redactValue redacts a field, and for maps only key or every key when key
+// is empty.
+func redactValue(v reflect.Value, key string) {
+	if v.Kind() != reflect.Map {
+		v.Set(redacted(v))
+		return
+	}
+
+	if v.IsNil() {
+		return
+	}
+
+	// copy the map, it is shared with the row the copy was made of
+	copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		value := iter.Value()
+		if key == "" || (iter.Key().Kind() == reflect.String && iter.Key().String() == key) {
+			value = redacted(value)
+		}
+
+		copied.SetMapIndex(iter.Key(), value)
+	}
+
+	v.Set(copied)
+}
+
+func redacted(v reflect.Value) reflect.Value {
+	switch {
+	case v.IsZero():
+		return v
+	case v.Kind() == reflect.String:
+		return reflect.ValueOf(redactedValue).Convert(v.Type())
+	default:
+		return reflect.Zero(v.Type())
+	}
+}
+
+// Redact returns the rows of an entity with the fields redacted by the
+// settings, for rows that reach frames other than the rows of the query
+// entity, such as the build status of forecasts.
+func Redact[T ~[]E, E any](entity string, rows T, settings models.Settings) T {
+	return redactRows(rows, compileRedactions(entity, settings))
+}
+
+// sampleColumns maps the build status columns to the sample fields recorded
+// from them.
+var sampleColumns = map[string]string{
+	"Active":                  "Active",
+	"Enqueued":                "Enqueued",
+	"PendingConcurrency":      "PendingConcurrency",
+	"Minutes.Current":         "MinutesCurrent",
+	"Minutes.IncludedMinutes": "MinutesIncluded",
+}
+
+// sampleRedactions returns the sample fields recorded from the redacted
+// fields of the build status.
+func sampleRedactions(settings models.Settings) []fieldRef {
+	redactions := []fieldRef{}
+	for _, ref := range compileRedactions("builds-account", settings) {
+		name, ok := sampleColumns[ref.name()]
+		if !ok {
+			continue
+		}
+
+		if sampleRef, ok := seriesSchemas["builds-account"].lookup(name); ok {
+			redactions = append(redactions, sampleRef)
+		}
+	}
+
+	return redactions
+}
+
+// labelRedactor redacts the labels of frames computed from rows, e.g. the
+// site of DORA metrics, when the row field a label comes from is redacted.
+// The rows are computed on unredacted, so sites never merge. Distinct
+// values become distinct numbered redactions, the same in every frame of a
+// response, so rows and series of different sites stay apart.
+type labelRedactor struct {
+	fields map[string]bool
+	// labels are the redactions of the values of every field
+	labels map[string]map[string]string
+}
+
+// newLabelRedactor returns the redactor of the labels of rows of entity,
+// sources maps the label fields to the row fields they come from.
+func newLabelRedactor(entity string, settings models.Settings, sources map[string]string) *labelRedactor {
+	r := &labelRedactor{fields: map[string]bool{}, labels: map[string]map[string]string{}}
+	for _, ref := range compileRedactions(entity, settings) {
+		for label, source := range sources {
+			if ref.name() == source {
+				r.fields[label] = true
+			}
+		}
+	}
+
+	return r
+}
+
+// redact replaces the values of the redacted label fields of the frame,
+// except the values to keep such as the row of every site.
+func (r *labelRedactor) redact(frame *data.Frame, keep ...string) {
+	for _, field := range frame.Fields {
+		if !r.fields[field.Name] || field.Type() != data.FieldTypeString {
+			continue
+		}
+
+		for i := 0; i < field.Len(); i++ {
+			value := field.At(i).(string)
+			if value == "" || slices.Contains(keep, value) {
+				continue
+			}
+
+			field.Set(i, r.label(field.Name, value))
+		}
+	}
+}
+
+func (r *labelRedactor) label(field string, value string) string {
+	labels, ok := r.labels[field]
+	if !ok {
+		labels = map[string]string{}
+		r.labels[field] = labels
+	}
+
+	label, ok := labels[value]
+	if !ok {
+		label = fmt.Sprintf("%s %d", redactedValue, len(labels)+1)
+		labels[value] = label
+	}
+
+	return label
+}
@@ -0,0 +1,215 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+	"github.com/grafana/netlify-datasource/pkg/plugin/sampler"
+)
+
+func TestRedactRows(t *testing.T) {
+	site := client.Site{ID: "site-1", Password: "hunter2", DeployHook: "https://api.netlify.com/hooks/abc"}
+	site.DefaultHooksData.AccessToken = "token"
+	site.BuildSettings.Env.Property1 = "secret"
+	sites := client.SitesResponse{site, {ID: "site-2"}}
+
+	t.Run("redacts the built-in fields", func(t *testing.T) {
+		redacted := redactRows(sites, compileRedactions("sites", models.Settings{}))
+
+		assert.Equal(t, "site-1", redacted[0].ID)
+		assert.Equal(t, redactedValue, redacted[0].Password)
+		assert.Equal(t, redactedValue, redacted[0].DeployHook)
+		assert.Equal(t, redactedValue, redacted[0].DefaultHooksData.AccessToken)
+		assert.Equal(t, redactedValue, redacted[0].BuildSettings.Env.Property1)
+		assert.Empty(t, redacted[1].Password)
+		assert.Equal(t, "hunter2", sites[0].Password)
+	})
+
+	t.Run("redacts configured fields of every entity", func(t *testing.T) {
+		settings := models.Settings{RedactFields: []string{"notification_email", "data.email", "Password"}}
+
+		redacted := redactRows(client.SitesResponse{{NotificationEmail: "ops@example.com"}}, compileRedactions("sites", settings))
+		assert.Equal(t, redactedValue, redacted[0].NotificationEmail)
+
+		submissions := client.FormSubmissionsResponse{{Data: map[string]string{"email": "jane@example.com", "rating": "4"}}}
+		redactedSubmissions := redactRows(submissions, compileRedactions("form-submissions", settings))
+		assert.Equal(t, map[string]string{"email": redactedValue, "rating": "4"}, redactedSubmissions[0].Data)
+		assert.Equal(t, "jane@example.com", submissions[0].Data["email"])
+	})
+
+	t.Run("opting out shows every field", func(t *testing.T) {
+		redacted := redactRows(sites, compileRedactions("sites", models.Settings{DisableRedaction: true}))
+		assert.Equal(t, "hunter2", redacted[0].Password)
+	})
+}
+
+func TestRedactedSitesQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":"site-1","password":"hunter2"},{"id":"site-2","password":"letmein"}]`)
+	}))
+	defer server.Close()
+
+	handler := NewQueryHandler(newTestClient(t, models.Settings{BaseUrl: server.URL}), nil, nil, nil)
+
+	t.Run("frames hold redacted values", func(t *testing.T) {
+		res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON: []byte(`{"entity":"sites","parsingOptions":{"selectedFields":["ID","Password"]}}`),
+		})
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 1)
+
+		field, _ := res.Frames[0].FieldByName("Password")
+		require.NotNil(t, field)
+		require.Equal(t, 2, field.Len())
+		assert.Equal(t, redactedValue, *field.At(0).(*string))
+		assert.Equal(t, redactedValue, *field.At(1).(*string))
+	})
+
+	t.Run("filters cannot match redacted values", func(t *testing.T) {
+		res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON: []byte(`{"entity":"sites","filters":[{"field":"password","operator":"=~","value":"^hunter"}]}`),
+		})
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 1)
+		assert.Zero(t, res.Frames[0].Rows())
+	})
+}
+
+func TestRedactedComputedFrames(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sites/site-a/deploys":
+			fmt.Fprint(w, `[{"id":"a-1","name":"secret-a","branch":"secret-branch","state":"error","context":"production","deploy_time":60,"created_at":"2024-01-02T00:00:00Z"}]`)
+		case "/sites/site-b/deploys":
+			fmt.Fprint(w, `[{"id":"b-1","name":"secret-b","branch":"secret-branch","state":"ready","context":"production","deploy_time":120,"created_at":"2024-01-03T00:00:00Z","published_at":"2024-01-03T00:01:00Z"}]`)
+		case "/sites/site-a/builds", "/sites/site-b/builds":
+			fmt.Fprint(w, `[]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	timeRange := backend.TimeRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	query := func(t *testing.T, settings models.Settings, entity string) data.Frames {
+		settings.BaseUrl = server.URL
+		res := NewQueryHandler(newTestClient(t, settings), nil, nil, nil).Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON:      []byte(fmt.Sprintf(`{"entity":%q,"siteId":"{site-a,site-b}"}`, entity)),
+			TimeRange: timeRange,
+		})
+		require.NoError(t, res.Error)
+		return res.Frames
+	}
+	redacting := models.Settings{RedactFields: []string{"SiteID", "Name", "Branch"}}
+
+	t.Run("build minutes by branch", func(t *testing.T) {
+		frames := query(t, redacting, "build-minutes-by-site")
+		require.Len(t, frames, 2)
+
+		byBranch := frames[1]
+		for name, values := range map[string][]string{
+			"Site":     {"[redacted] 1", "[redacted] 2"},
+			"SiteName": {"[redacted] 1", "[redacted] 2"},
+			"Branch":   {"[redacted] 1", "[redacted] 1"},
+			"Context":  {"production", "production"},
+		} {
+			field, _ := byBranch.FieldByName(name)
+			require.NotNil(t, field, name)
+			assert.Equal(t, values, fieldValues[string](field), name)
+		}
+
+		minutes, _ := byBranch.FieldByName("Minutes")
+		assert.Equal(t, []float64{2, 1}, fieldValues[float64](minutes))
+	})
+
+	t.Run("dora keeps sites apart", func(t *testing.T) {
+		stats := query(t, redacting, "dora")[0]
+		plain := query(t, models.Settings{}, "dora")[0]
+
+		site, _ := stats.FieldByName("Site")
+		require.NotNil(t, site)
+		assert.Equal(t, []string{"[redacted] 1", "[redacted] 2", doraAllSites}, fieldValues[string](site))
+
+		// a failure of one site is not restored by a deploy of another
+		for _, name := range []string{"Deployments", "ChangeFailureRate", "TimeToRestore"} {
+			redacted, _ := stats.FieldByName(name)
+			unredacted, _ := plain.FieldByName(name)
+			for i := 0; i < redacted.Len(); i++ {
+				assert.Equal(t, unredacted.At(i), redacted.At(i), name)
+			}
+		}
+
+		restore, _ := stats.FieldByName("TimeToRestore")
+		assert.Nil(t, restore.At(2).(*float64))
+	})
+}
+
+func TestRedactedSamples(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"active":2,"enqueued":3,"minutes":{"current":777,"included_minutes":1000,"period_start_date":"2024-01-01T00:00:00Z","period_end_date":"2099-01-01T00:00:00Z"}}`)
+	}))
+	defer server.Close()
+
+	fetch := func(ctx context.Context) (client.BuildAccountResponse, client.Meta, error) {
+		status := client.BuildAccountResponse{Active: 2, Enqueued: 3}
+		status.Minutes.Current = 777
+		status.Minutes.IncludedMinutes = 1000
+		return status, client.Meta{}, nil
+	}
+
+	samples := sampler.Start(fetch, time.Hour, 10)
+	defer samples.Close()
+	require.Eventually(t, func() bool { return len(samples.Samples(time.Time{}, time.Time{})) == 1 }, time.Second, time.Millisecond)
+
+	settings := models.Settings{BaseUrl: server.URL, AccountId: "my-account", RedactFields: []string{"Minutes.Current", "Enqueued"}}
+	handler := NewQueryHandler(newTestClient(t, settings), nil, nil, samples)
+
+	t.Run("build status series", func(t *testing.T) {
+		res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON:      []byte(`{"entity":"builds-account","series":true}`),
+			TimeRange: backend.TimeRange{From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Minute)},
+		})
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 1)
+
+		for name, value := range map[string]int64{"Active": 2, "Enqueued": 0, "MinutesCurrent": 0, "MinutesIncluded": 1000} {
+			field, _ := res.Frames[0].FieldByName(name)
+			require.NotNil(t, field, name)
+			assert.Equal(t, value, *field.At(0).(*int64), name)
+		}
+	})
+
+	t.Run("forecast", func(t *testing.T) {
+		res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON: []byte(`{"entity":"build-minutes-forecast"}`),
+		})
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 2)
+
+		current, _ := res.Frames[0].FieldByName("Current")
+		require.NotNil(t, current)
+		assert.Equal(t, []int64{0}, fieldValues[int64](current))
+
+		actual, _ := res.Frames[1].FieldByName("Actual")
+		require.NotNil(t, actual)
+		for i := 0; i < actual.Len(); i++ {
+			if value := actual.At(i).(*float64); value != nil {
+				assert.Zero(t, *value)
+			}
+		}
+	})
+}
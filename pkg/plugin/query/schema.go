@@ -0,0 +1,279 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/sampler"
+)
+
+// column is a field of an entity row, named the way framestruct names it in
+// the data frame: the Go field name, prefixed with the parent names of
+// nested structs.
+type column struct {
+	name     string
+	jsonName string
+	index    []int
+	typ      reflect.Type
+	// dynamic columns are maps, every key becomes its own "name.key" field.
+	dynamic bool
+}
+
+// fieldRef is a field named by a query: a column and, for dynamic columns,
+// the map key.
+type fieldRef struct {
+	column
+	key string
+}
+
+// name returns the frame name of the field.
+func (f fieldRef) name() string {
+	if f.dynamic {
+		return f.column.name + "." + f.key
+	}
+
+	return f.column.name
+}
+
+// value returns the value of the field in row, or an invalid value when a
+// map has no such key.
+func (f fieldRef) value(row reflect.Value) reflect.Value {
+	v := row.FieldByIndex(f.index)
+	if f.dynamic {
+		return v.MapIndex(reflect.ValueOf(f.key))
+	}
+
+	return v
+}
+
+// schema lists the columns of an entity in frame order.
+type schema []column
+
+// entitySchemas maps every entity of the Query switch to the columns of its
+// rows.
+var entitySchemas = map[string]schema{
+	"builds":           schemaOf[client.Build](),
+	"deployments":      schemaOf[client.Deploy](),
+	"forms":            schemaOf[client.Form](),
+	"form-submissions": schemaOf[client.FormSubmission](),
+	"builds-account":   schemaOf[client.BuildAccountResponse](),
+	"sites":            schemaOf[client.Site](),
+	"accounts":         schemaOf[client.Account](),
+	// annotations are filtered and sorted on the deploys they are made of
+	"deploy-annotations": schemaOf[client.Deploy](),
+}
+
+// seriesSchemas maps the entities with a series mode to the columns of the
+// samples their series are made of.
+var seriesSchemas = map[string]schema{
+	"builds-account": schemaOf[sampler.Sample](),
+}
+
+// frameSchemas returns an empty frame of the entities that compute their
+// frames instead of converting rows, only selectedFields apply to them.
+var frameSchemas = map[string]func() *data.Frame{
+	"dora":                   func() *data.Frame { return newDoraFrame("dora", false) },
+	"build-minutes-forecast": func() *data.Frame { return forecastFrame(client.BuildAccountResponse{}, nil) },
+	"build-minutes-by-site":  func() *data.Frame { return usageFrame("build_minutes_by_branch", nil, true) },
+}
+
+func schemaOf[E any]() schema {
+	return appendColumns(nil, reflect.TypeOf((*E)(nil)).Elem(), nil, "", "")
+}
+
+func appendColumns(columns schema, t reflect.Type, index []int, prefix string, jsonPrefix string) schema {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("frame") == "-" {
+			continue
+		}
+
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName == "" {
+			jsonName = field.Name
+		}
+
+		col := column{
+			name:     prefix + field.Name,
+			jsonName: jsonPrefix + jsonName,
+			index:    append(append([]int{}, index...), i),
+			typ:      field.Type,
+			dynamic:  field.Type.Kind() == reflect.Map,
+		}
+
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
+			columns = appendColumns(columns, field.Type, col.index, col.name+".", col.jsonName+".")
+			continue
+		}
+
+		columns = append(columns, col)
+	}
+
+	return columns
+}
+
+// lookup finds a field by its frame name or its Netlify API name, ignoring
+// case. Keys of map fields are named "name.key".
+func (s schema) lookup(name string) (fieldRef, bool) {
+	for _, col := range s {
+		if !col.dynamic {
+			if strings.EqualFold(name, col.name) || strings.EqualFold(name, col.jsonName) {
+				return fieldRef{column: col}, true
+			}
+
+			continue
+		}
+
+		for _, prefix := range []string{col.name + ".", col.jsonName + "."} {
+			if len(name) > len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+				return fieldRef{column: col, key: name[len(prefix):]}, true
+			}
+		}
+	}
+
+	return fieldRef{}, false
+}
+
+// names returns the frame names of every column.
+func (s schema) names() []string {
+	names := make([]string, 0, len(s))
+	for _, col := range s {
+		if col.dynamic {
+			names = append(names, col.name+".<key>")
+			continue
+		}
+
+		names = append(names, col.name)
+	}
+
+	return names
+}
+
+// field looks up a field named by a query on entity.
+func (s schema) field(entity string, name string) (fieldRef, error) {
+	ref, ok := s.lookup(name)
+	if !ok {
+		return fieldRef{}, fmt.Errorf("unknown field %q for entity %q, expected one of: %s", name, entity, strings.Join(s.names(), ", "))
+	}
+
+	return ref, nil
+}
+
+// resolveFields validates the fields named by a query against the schema of
+// its entity and returns their frame names.
+func resolveFields(entity string, fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	// annotations have the fixed fields Grafana reads them from
+	if entity == "deploy-annotations" {
+		return nil, fmt.Errorf("entity %q does not support selectedFields", entity)
+	}
+
+	s, ok := entitySchemas[entity]
+	if !ok {
+		return resolveFrameFields(entity, fields)
+	}
+
+	return s.resolve(entity, fields)
+}
+
+// resolveSeriesFields validates the fields named by a series query against
+// the schema of the samples of its entity and returns their frame names.
+func resolveSeriesFields(entity string, fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	s, ok := seriesSchemas[entity]
+	if !ok {
+		return nil, fmt.Errorf("entity %q has no series", entity)
+	}
+
+	return s.resolve(entity, fields)
+}
+
+// resolve returns the frame names of the fields, failing on the first
+// unknown one.
+func (s schema) resolve(entity string, fields []string) ([]string, error) {
+	resolved := make([]string, 0, len(fields))
+	for _, field := range fields {
+		ref, err := s.field(entity, field)
+		if err != nil {
+			return nil, err
+		}
+
+		resolved = append(resolved, ref.name())
+	}
+
+	return resolved, nil
+}
+
+// selectFields keeps only the selected fields of the frame, in the selected
+// order. Selected fields missing from the frame, e.g. because there are no
+// rows, are skipped.
+func selectFields(frame *data.Frame, selected []string) {
+	if len(selected) == 0 {
+		return
+	}
+
+	fields := make([]*data.Field, 0, len(selected))
+	seen := map[string]bool{}
+	for _, name := range selected {
+		field, _ := frame.FieldByName(name)
+		if field == nil || seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		fields = append(fields, field)
+	}
+
+	frame.Fields = fields
+}
+
+// frameFields returns the selected fields the frame has, a frame without any
+// of them keeps every field.
+func frameFields(frame *data.Frame, selected []string) []string {
+	fields := []string{}
+	for _, name := range selected {
+		if field, _ := frame.FieldByName(name); field != nil {
+			fields = append(fields, name)
+		}
+	}
+
+	return fields
+}
+
+// resolveFrameFields returns the frame names of the selected fields of an
+// entity computing its frames, ignoring case.
+func resolveFrameFields(entity string, fields []string) ([]string, error) {
+	newFrame, ok := frameSchemas[entity]
+	if !ok {
+		return nil, fmt.Errorf("entity %q does not support selectedFields", entity)
+	}
+
+	names := []string{}
+	for _, field := range newFrame().Fields {
+		names = append(names, field.Name)
+	}
+
+	resolved := make([]string, 0, len(fields))
+	for _, field := range fields {
+		i := slices.IndexFunc(names, func(name string) bool { return strings.EqualFold(name, field) })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown field %q for entity %q, expected one of: %s", field, entity, strings.Join(names, ", "))
+		}
+
+		resolved = append(resolved, names[i])
+	}
+
+	return resolved, nil
+}
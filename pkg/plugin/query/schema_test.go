@@ -0,0 +1,85 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+func TestResolveFields(t *testing.T) {
+	t.Run("accepts frame and api names", func(t *testing.T) {
+		fields, err := resolveFields("sites", []string{"Name", "custom_domain", "publisheddeploy.id", "PublishedDeploy.State"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Name", "CustomDomain", "PublishedDeploy.ID", "PublishedDeploy.State"}, fields)
+	})
+
+	t.Run("accepts map keys", func(t *testing.T) {
+		fields, err := resolveFields("form-submissions", []string{"data.email"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Data.email"}, fields)
+	})
+
+	t.Run("accepts fields of computed frames", func(t *testing.T) {
+		fields, err := resolveFields("dora", []string{"site", "LeadTime"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Site", "LeadTime"}, fields)
+	})
+
+	t.Run("rejects unknown fields of computed frames", func(t *testing.T) {
+		_, err := resolveFields("dora", []string{"Bogus"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown field "Bogus" for entity "dora"`)
+
+		_, err = resolveFields("build-minutes-forecast", []string{"Bogus"})
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects fields of annotations", func(t *testing.T) {
+		_, err := resolveFields("deploy-annotations", []string{"ID"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `entity "deploy-annotations" does not support selectedFields`)
+	})
+
+	t.Run("rejects unknown fields", func(t *testing.T) {
+		_, err := resolveFields("builds", []string{"ID", "Commit"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown field "Commit" for entity "builds"`)
+		assert.Contains(t, err.Error(), "DeployID")
+	})
+}
+
+func TestSelectFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":"build-1","deploy_id":"deploy-1","sha":"abc","done":true}]`)
+	}))
+	defer server.Close()
+
+	handler := NewQueryHandler(newTestClient(t, models.Settings{BaseUrl: server.URL}), nil, nil, nil)
+
+	t.Run("returns the selected fields in order", func(t *testing.T) {
+		res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON: []byte(`{"entity":"builds","siteId":"my-site","parsingOptions":{"selectedFields":["sha","ID"]}}`),
+		})
+		require.NoError(t, res.Error)
+		require.Len(t, res.Frames, 1)
+		require.Len(t, res.Frames[0].Fields, 2)
+		assert.Equal(t, "Sha", res.Frames[0].Fields[0].Name)
+		assert.Equal(t, "ID", res.Frames[0].Fields[1].Name)
+	})
+
+	t.Run("rejects unknown fields", func(t *testing.T) {
+		res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+			JSON: []byte(`{"entity":"builds","siteId":"my-site","parsingOptions":{"selectedFields":["Commit"]}}`),
+		})
+		assert.Equal(t, backend.StatusBadRequest, res.Status)
+		assert.Contains(t, res.Error.Error(), `unknown field "Commit"`)
+	})
+}
@@ -0,0 +1,117 @@
+package query
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	directionAsc  = "asc"
+	directionDesc = "desc"
+)
+
+// rowOrder is the orderBy of a query validated against the schema of its
+// entity.
+type rowOrder struct {
+	field      fieldRef
+	descending bool
+}
+
+// compileOrder validates the orderBy and direction of a query, it returns
+// nil when the rows keep their order.
+func compileOrder(entity string, orderBy string, direction string) (*rowOrder, error) {
+	direction = strings.ToLower(direction)
+	if direction != "" && direction != directionAsc && direction != directionDesc {
+		return nil, fmt.Errorf("unknown direction %q, expected %q or %q", direction, directionAsc, directionDesc)
+	}
+
+	if orderBy == "" {
+		return nil, nil
+	}
+
+	s, ok := entitySchemas[entity]
+	if !ok {
+		return nil, fmt.Errorf("entity %q does not support orderBy", entity)
+	}
+
+	field, err := s.field(entity, orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rowOrder{field: field, descending: direction == directionDesc}, nil
+}
+
+// sortRows sorts the rows by the order field, rows with equal values keep
+// their order.
+func sortRows[T ~[]E, E any](rows T, order *rowOrder) T {
+	if order == nil {
+		return rows
+	}
+
+	sorted := slices.Clone(rows)
+	slices.SortStableFunc(sorted, func(a, b E) int {
+		result := compareValues(order.field.value(reflect.ValueOf(a)), order.field.value(reflect.ValueOf(b)))
+		if order.descending {
+			return -result
+		}
+
+		return result
+	})
+
+	return sorted
+}
+
+// pageRows skips the first offset rows and keeps at most limit rows, a zero
+// limit keeps every row.
+func pageRows[T ~[]E, E any](rows T, offset int, limit int) T {
+	rows = rows[min(offset, len(rows)):]
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+
+	return rows
+}
+
+// compareValues orders two values of the same field. Missing map keys come
+// first, map values are compared as numbers when both are numeric.
+func compareValues(a, b reflect.Value) int {
+	switch {
+	case !a.IsValid() || !b.IsValid():
+		return cmp.Compare(boolRank(a.IsValid()), boolRank(b.IsValid()))
+	case a.Kind() == reflect.Bool:
+		return cmp.Compare(boolRank(a.Bool()), boolRank(b.Bool()))
+	case a.Kind() == reflect.String:
+		x, errA := strconv.ParseFloat(a.String(), 64)
+		y, errB := strconv.ParseFloat(b.String(), 64)
+		if errA == nil && errB == nil {
+			return cmp.Compare(x, y)
+		}
+
+		return strings.Compare(a.String(), b.String())
+	}
+
+	if t, ok := a.Interface().(time.Time); ok {
+		return t.Compare(b.Interface().(time.Time))
+	}
+
+	if x, ok := numberValue(a); ok {
+		y, _ := numberValue(b)
+		return cmp.Compare(x, y)
+	}
+
+	return strings.Compare(formatValue(a), formatValue(b))
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
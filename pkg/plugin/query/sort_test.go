@@ -0,0 +1,99 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+func TestSortRows(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	deploys := client.DeploysResponse{
+		{ID: "deploy-2", DeployTime: 90, CreatedAt: day(2)},
+		{ID: "deploy-3", DeployTime: 30, CreatedAt: day(3)},
+		{ID: "deploy-1", DeployTime: 30, CreatedAt: day(1)},
+	}
+
+	ids := func(deploys client.DeploysResponse) string {
+		ids := []string{}
+		for _, deploy := range deploys {
+			ids = append(ids, deploy.ID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	tests := []struct {
+		orderBy   string
+		direction string
+		expected  string
+	}{
+		{"created_at", "", "deploy-1,deploy-2,deploy-3"},
+		{"CreatedAt", "desc", "deploy-3,deploy-2,deploy-1"},
+		{"DeployTime", "asc", "deploy-3,deploy-1,deploy-2"},
+		{"id", "DESC", "deploy-3,deploy-2,deploy-1"},
+		{"", "desc", "deploy-2,deploy-3,deploy-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.orderBy+" "+tt.direction, func(t *testing.T) {
+			order, err := compileOrder("deployments", tt.orderBy, tt.direction)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, ids(sortRows(deploys, order)))
+		})
+	}
+
+	t.Run("rejects unknown fields and directions", func(t *testing.T) {
+		_, err := compileOrder("deployments", "Commit", "")
+		assert.ErrorContains(t, err, `unknown field "Commit"`)
+
+		_, err = compileOrder("deployments", "ID", "up")
+		assert.ErrorContains(t, err, `unknown direction "up"`)
+	})
+}
+
+func TestPageRows(t *testing.T) {
+	rows := []int{1, 2, 3, 4, 5}
+
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, pageRows(rows, 0, 0))
+	assert.Equal(t, []int{1, 2}, pageRows(rows, 0, 2))
+	assert.Equal(t, []int{3, 4}, pageRows(rows, 2, 2))
+	assert.Equal(t, []int{5}, pageRows(rows, 4, 10))
+	assert.Empty(t, pageRows(rows, 10, 2))
+}
+
+func TestSortMergedSites(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sites/site-a/deploys":
+			fmt.Fprint(w, `[{"id":"a-2","state":"error","created_at":"2024-01-04T00:00:00Z"},{"id":"a-1","state":"error","created_at":"2024-01-01T00:00:00Z"}]`)
+		case "/sites/site-b/deploys":
+			fmt.Fprint(w, `[{"id":"b-2","state":"ready","created_at":"2024-01-03T00:00:00Z"},{"id":"b-1","state":"error","created_at":"2024-01-02T00:00:00Z"}]`)
+		}
+	}))
+	defer server.Close()
+
+	handler := NewQueryHandler(newTestClient(t, models.Settings{BaseUrl: server.URL}), nil, nil, nil)
+
+	res := handler.Query(context.Background(), backend.PluginContext{}, backend.DataQuery{
+		JSON: []byte(`{"entity":"deployments","siteId":"{site-a,site-b}","filters":[{"field":"state","operator":"==","value":"error"}],"orderBy":"created_at","direction":"desc","limit":2}`),
+	})
+	require.NoError(t, res.Error)
+	require.Len(t, res.Frames, 1)
+
+	field, _ := res.Frames[0].FieldByName("ID")
+	require.NotNil(t, field)
+	require.Equal(t, 2, field.Len())
+	assert.Equal(t, "a-2", *field.At(0).(*string))
+	assert.Equal(t, "b-1", *field.At(1).(*string))
+}
@@ -0,0 +1,78 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana-plugin-sdk-go/data/framestruct"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+	"github.com/grafana/netlify-datasource/pkg/plugin/stream"
+)
+
+// streamOptions are the options of a live query its stream applies to the
+// deploys it sends, so they fit the frame the query returned.
+type streamOptions struct {
+	SelectedFields []string `json:"selectedFields,omitempty"`
+	Filters        []filter `json:"filters,omitempty"`
+}
+
+// registerStreamOptions registers the options of a live query with the
+// streams and returns their key, empty when the query has none.
+func registerStreamOptions(qm queryModel) (string, error) {
+	options := streamOptions{SelectedFields: qm.ParsingOptions.SelectedFields, Filters: qm.Filters}
+	if len(options.SelectedFields) == 0 && len(options.Filters) == 0 {
+		return "", nil
+	}
+
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return "", err
+	}
+
+	return stream.RegisterOptions(encoded), nil
+}
+
+// StreamFrame returns the frame of deploys a live query streams, redacted,
+// filtered and projected like the rows of the query with the options
+// registered under optionsKey. It is nil when no deploy passes the filters.
+func StreamFrame(optionsKey string, deploys client.DeploysResponse, settings models.Settings) (*data.Frame, error) {
+	var options streamOptions
+	if optionsKey != "" {
+		encoded, ok := stream.Options(optionsKey)
+		if !ok {
+			return nil, fmt.Errorf("unknown options %q of the live query, run the query again", optionsKey)
+		}
+
+		if err := json.Unmarshal(encoded, &options); err != nil {
+			return nil, fmt.Errorf("invalid options of the live query: %w", err)
+		}
+	}
+
+	selected, err := resolveFields("deployments", options.SelectedFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selectedFields of the live query: %w", err)
+	}
+
+	filters, err := compileFilters("deployments", options.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filters of the live query: %w", err)
+	}
+
+	deploys = redactRows(deploys, compileRedactions("deployments", settings))
+	deploys = filterRows(deploys, filters)
+	if len(deploys) == 0 {
+		return nil, nil
+	}
+
+	frame, err := framestruct.ToDataFrame("deployments", deploys)
+	if err != nil {
+		return nil, fmt.Errorf("failed deployments to frame conversion: %w", err)
+	}
+
+	selectFields(frame, selected)
+
+	return frame, nil
+}
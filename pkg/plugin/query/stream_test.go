@@ -0,0 +1,72 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/live"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+	"github.com/grafana/netlify-datasource/pkg/plugin/stream"
+)
+
+func TestStreamFrame(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":"deploy-1","state":"ready","context":"production"}]`)
+	}))
+	defer server.Close()
+
+	settings := models.Settings{BaseUrl: server.URL}
+	res := NewQueryHandler(newTestClient(t, settings), nil, nil, nil).Query(context.Background(), backend.PluginContext{
+		DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{UID: "netlify"},
+	}, backend.DataQuery{
+		JSON: []byte(`{"entity":"deployments","siteId":"my-site","live":true,
+			"filters":[{"field":"context","operator":"==","value":"production"}],
+			"parsingOptions":{"selectedFields":["id","State"]}}`),
+	})
+	require.NoError(t, res.Error)
+	require.Len(t, res.Frames, 1)
+
+	channel, err := live.ParseChannel(res.Frames[0].Meta.Channel)
+	require.NoError(t, err)
+	require.True(t, channel.IsValid())
+
+	siteId, optionsKey, ok := stream.ParseDeploysPath(channel.Path)
+	require.True(t, ok)
+	assert.Equal(t, "my-site", siteId)
+
+	t.Run("packets match the frame of the query", func(t *testing.T) {
+		frame, err := StreamFrame(optionsKey, client.DeploysResponse{
+			{ID: "deploy-2", State: "building", Context: "production"},
+			{ID: "deploy-3", State: "ready", Context: "deploy-preview"},
+		}, settings)
+		require.NoError(t, err)
+		require.NotNil(t, frame)
+
+		fields := []string{}
+		for _, field := range frame.Fields {
+			fields = append(fields, field.Name)
+		}
+		assert.Equal(t, []string{"ID", "State"}, fields)
+		assert.Equal(t, 1, frame.Rows())
+	})
+
+	t.Run("skips packets without matching deploys", func(t *testing.T) {
+		frame, err := StreamFrame(optionsKey, client.DeploysResponse{{ID: "deploy-3", Context: "deploy-preview"}}, settings)
+		require.NoError(t, err)
+		assert.Nil(t, frame)
+	})
+
+	t.Run("rejects unknown options", func(t *testing.T) {
+		_, err := StreamFrame("unknown", client.DeploysResponse{{ID: "deploy-1"}}, settings)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "run the query again")
+	})
+}
@@ -0,0 +1,31 @@
+package query
+
+import (
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// inTimeRange keeps the rows created within the query time range. Queries
+// without a time range, e.g. variable queries, keep every row.
+func inTimeRange[T ~[]E, E any](rows T, timeRange backend.TimeRange, createdAt func(E) time.Time) T {
+	if timeRange.From.IsZero() && timeRange.To.IsZero() {
+		return rows
+	}
+
+	filtered := make(T, 0, len(rows))
+	for _, row := range rows {
+		created := createdAt(row)
+		if !timeRange.From.IsZero() && created.Before(timeRange.From) {
+			continue
+		}
+
+		if !timeRange.To.IsZero() && created.After(timeRange.To) {
+			continue
+		}
+
+		filtered = append(filtered, row)
+	}
+
+	return filtered
+}
@@ -0,0 +1,29 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+func TestInTimeRange(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	deploys := client.DeploysResponse{
+		{ID: "deploy-3", CreatedAt: day(3)},
+		{ID: "deploy-2", CreatedAt: day(2)},
+		{ID: "deploy-1", CreatedAt: day(1)},
+	}
+
+	t.Run("keeps rows created within the range", func(t *testing.T) {
+		filtered := inTimeRange(deploys, backend.TimeRange{From: day(2), To: day(2).Add(time.Hour)}, client.Deploy.Created)
+		assert.Equal(t, client.DeploysResponse{deploys[1]}, filtered)
+	})
+
+	t.Run("keeps every row without a range", func(t *testing.T) {
+		assert.Equal(t, deploys, inTimeRange(deploys, backend.TimeRange{}, client.Deploy.Created))
+	})
+}
@@ -2,27 +2,35 @@ package resources
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/events"
+	"github.com/grafana/netlify-datasource/pkg/plugin/stream"
 )
 
 type ResourceHandler struct {
-	client client.Client
-	Router http.Handler
+	client  client.Client
+	streams *stream.Hub
+	events  *events.Store
+	Router  http.Handler
 }
 
 func getRoutes(h *ResourceHandler) *http.ServeMux {
 	router := http.NewServeMux()
 
 	router.HandleFunc("/sites", h.HandleGetSites)
+	router.HandleFunc("/webhooks/netlify", h.HandleWebhook)
 
 	return router
 }
 
-func NewResourcesHandler(client client.Client) ResourceHandler {
+func NewResourcesHandler(client client.Client, streams *stream.Hub, events *events.Store) ResourceHandler {
 	r := ResourceHandler{
-		client: client,
+		client:  client,
+		streams: streams,
+		events:  events,
 	}
 
 	r.Router = getRoutes(&r)
@@ -35,11 +43,11 @@ func (h *ResourceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ResourceHandler) HandleGetSites(w http.ResponseWriter, r *http.Request) {
-	sites, err := h.client.GetSites()
+	sites, _, err := h.client.GetSites(r.Context())
 	if err != nil {
-		// handle error
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(errorStatusCode(err))
 		w.Write([]byte(err.Error()))
+		return
 	}
 
 	res := make([]string, len(sites))
@@ -57,3 +65,21 @@ func (h *ResourceHandler) HandleGetSites(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 	w.Write(response)
 }
+
+// errorStatusCode passes the status of a Netlify API error on to the caller,
+// other failures are reported as bad gateway. Netlify rejecting the token is
+// reported as bad gateway too, the frontend takes a 401 or 403 for an expired
+// Grafana session.
+func errorStatusCode(err error) int {
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		return http.StatusBadGateway
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return http.StatusBadGateway
+	default:
+		return apiErr.StatusCode
+	}
+}
@@ -0,0 +1,47 @@
+package resources
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+func TestHandleGetSites(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		expected int
+	}{
+		{"revoked token is not a grafana auth error", http.StatusUnauthorized, http.StatusBadGateway},
+		{"forbidden is not a grafana auth error", http.StatusForbidden, http.StatusBadGateway},
+		{"not found is passed on", http.StatusNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, `{"code":0,"message":"Access Denied"}`)
+			}))
+			defer server.Close()
+
+			c, err := client.NewClient(models.Settings{BaseUrl: server.URL}, httpclient.Options{})
+			require.NoError(t, err)
+			h := NewResourcesHandler(c, nil, nil)
+
+			rec := httptest.NewRecorder()
+			h.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sites", nil))
+
+			assert.Equal(t, tt.expected, rec.Code)
+			assert.Contains(t, rec.Body.String(), "Access Denied")
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package resources
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+// maxWebhookBody bounds the size of a webhook payload, deploy payloads are a
+// few kilobytes.
+const maxWebhookBody = 1 << 20
+
+var errInvalidSignature = errors.New("invalid webhook signature")
+
+// HandleWebhook receives Netlify deploy notifications. The payload is the
+// deploy, signed with the webhook secret in the X-Webhook-Signature JWS.
+// Verified deploys are published to the live streams of their site and
+// recorded for annotations.
+func (h *ResourceHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.client.WebhookSecret == "" {
+		http.Error(w, "webhooks are not configured, set a webhook secret in the data source settings", http.StatusForbidden)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read webhook payload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := verifySignature(r.Header.Get("X-Webhook-Signature"), body, h.client.WebhookSecret); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var deploy client.Deploy
+	if err := json.Unmarshal(body, &deploy); err != nil || deploy.ID == "" || deploy.SiteID == "" {
+		http.Error(w, "webhook payload is not a deploy", http.StatusBadRequest)
+		return
+	}
+
+	backend.Logger.Info("webhook", "siteId", deploy.SiteID, "deployId", deploy.ID, "state", deploy.State)
+
+	h.events.Add(deploy.SiteID, deploy)
+	h.streams.Publish(deploy.SiteID, client.DeploysResponse{deploy})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// signatureClaims are the claims of the JWS Netlify signs webhooks with.
+type signatureClaims struct {
+	Issuer string `json:"iss"`
+	Sha256 string `json:"sha256"`
+}
+
+// verifySignature checks an HS256 JWS issued by Netlify for the sha256 of
+// body.
+func verifySignature(signature string, body []byte, secret string) error {
+	parts := strings.Split(signature, ".")
+	if len(parts) != 3 {
+		return errInvalidSignature
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Algorithm != "HS256" {
+		return errInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
+		return errInvalidSignature
+	}
+
+	var claims signatureClaims
+	if err := decodeSegment(parts[1], &claims); err != nil || claims.Issuer != "netlify" {
+		return errInvalidSignature
+	}
+
+	digest := sha256.Sum256(body)
+	if !strings.EqualFold(claims.Sha256, hex.EncodeToString(digest[:])) {
+		return fmt.Errorf("%w: payload does not match", errInvalidSignature)
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(decoded, v)
+}
@@ -0,0 +1,84 @@
+package resources
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+	"github.com/grafana/netlify-datasource/pkg/plugin/events"
+	"github.com/grafana/netlify-datasource/pkg/plugin/models"
+)
+
+// sign returns the X-Webhook-Signature Netlify sends with body.
+func sign(body []byte, secret string) string {
+	digest := sha256.Sum256(body)
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"netlify","sha256":"` + hex.EncodeToString(digest[:]) + `"}`))
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(header + "." + claims))
+
+	return header + "." + claims + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestHandleWebhook(t *testing.T) {
+	payload, err := os.ReadFile("testdata/deploy_succeeded.json")
+	require.NoError(t, err)
+
+	newHandler := func(t *testing.T, secret string) (ResourceHandler, *events.Store) {
+		c, err := client.NewClient(models.Settings{WebhookSecret: secret}, httpclient.Options{})
+		require.NoError(t, err)
+
+		store := events.NewStore(events.DefaultMaxPerSite)
+		return NewResourcesHandler(c, nil, store), store
+	}
+
+	post := func(h ResourceHandler, body []byte, signature string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/netlify", bytes.NewReader(body))
+		req.Header.Set("X-Webhook-Signature", signature)
+
+		rec := httptest.NewRecorder()
+		h.Router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("records signed deploys", func(t *testing.T) {
+		h, store := newHandler(t, "my-secret")
+
+		rec := post(h, payload, sign(payload, "my-secret"))
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+		deploys := store.Deploys("3970e0fe-8564-4903-9a55-c5f8de49fb8b")
+		require.Len(t, deploys, 1)
+		assert.Equal(t, "ready", deploys[0].State)
+		assert.Equal(t, "Fix header layout on mobile", deploys[0].Title)
+	})
+
+	t.Run("rejects invalid signatures", func(t *testing.T) {
+		h, store := newHandler(t, "my-secret")
+
+		for _, signature := range []string{"", "not-a-jws", sign(payload, "other-secret"), sign([]byte(`{}`), "my-secret")} {
+			rec := post(h, payload, signature)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		}
+		assert.Empty(t, store.Deploys("3970e0fe-8564-4903-9a55-c5f8de49fb8b"))
+	})
+
+	t.Run("rejects webhooks without a secret", func(t *testing.T) {
+		h, _ := newHandler(t, "")
+
+		rec := post(h, payload, sign(payload, ""))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
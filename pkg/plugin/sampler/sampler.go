@@ -0,0 +1,135 @@
+package sampler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+// Sample is the build queue and the build minutes of the account at a time.
+type Sample struct {
+	Time               time.Time
+	Active             int64
+	Enqueued           int64
+	PendingConcurrency int64
+	MinutesCurrent     int64
+	MinutesIncluded    int64
+}
+
+// Sampler records the build status of the account on an interval, keeping
+// the latest samples in a ring buffer so queries can return them as time
+// series. Netlify only reports the current status.
+type Sampler struct {
+	fetch    func(ctx context.Context) (client.BuildAccountResponse, client.Meta, error)
+	interval time.Duration
+	cancel   context.CancelFunc
+
+	mu      sync.Mutex
+	samples []Sample
+	// next is where the next sample is written, once the buffer is full it
+	// is also the oldest sample.
+	next int
+	full bool
+}
+
+// Start samples the build status every interval until Close is called,
+// keeping the latest size samples.
+func Start(fetch func(ctx context.Context) (client.BuildAccountResponse, client.Meta, error), interval time.Duration, size int) *Sampler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Sampler{
+		fetch:    fetch,
+		interval: interval,
+		cancel:   cancel,
+		samples:  make([]Sample, max(size, 1)),
+	}
+
+	go s.run(ctx)
+
+	return s
+}
+
+// Samples returns the samples taken between from and to, oldest first. A
+// zero from or to leaves that side open.
+func (s *Sampler) Samples(from time.Time, to time.Time) []Sample {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ordered := s.samples[:s.next]
+	if s.full {
+		ordered = append(append([]Sample{}, s.samples[s.next:]...), s.samples[:s.next]...)
+	}
+
+	samples := []Sample{}
+	for _, sample := range ordered {
+		if (!from.IsZero() && sample.Time.Before(from)) || (!to.IsZero() && sample.Time.After(to)) {
+			continue
+		}
+
+		samples = append(samples, sample)
+	}
+
+	return samples
+}
+
+// Close stops sampling.
+func (s *Sampler) Close() {
+	if s == nil {
+		return
+	}
+
+	s.cancel()
+}
+
+func (s *Sampler) run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.sample(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sampler) sample(ctx context.Context) {
+	status, _, err := s.fetch(client.NoCache(ctx))
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		backend.Logger.Warn("failed to sample build status", "err", err.Error())
+		return
+	}
+
+	s.add(Sample{
+		Time:               time.Now().UTC(),
+		Active:             status.Active,
+		Enqueued:           status.Enqueued,
+		PendingConcurrency: status.PendingConcurrency,
+		MinutesCurrent:     status.Minutes.Current,
+		MinutesIncluded:    status.Minutes.IncludedMinutes,
+	})
+}
+
+func (s *Sampler) add(sample Sample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples[s.next] = sample
+	s.next = (s.next + 1) % len(s.samples)
+	if s.next == 0 {
+		s.full = true
+	}
+}
@@ -0,0 +1,76 @@
+package sampler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+func TestSampler(t *testing.T) {
+	t.Run("samples the build status", func(t *testing.T) {
+		var calls atomic.Int64
+		fetch := func(ctx context.Context) (client.BuildAccountResponse, client.Meta, error) {
+			status := client.BuildAccountResponse{Active: 1, Enqueued: calls.Add(1)}
+			status.Minutes.Current = 42
+			return status, client.Meta{}, nil
+		}
+
+		s := Start(fetch, 5*time.Millisecond, 10)
+		defer s.Close()
+
+		require.Eventually(t, func() bool { return len(s.Samples(time.Time{}, time.Time{})) >= 3 }, time.Second, time.Millisecond)
+
+		samples := s.Samples(time.Time{}, time.Time{})
+		assert.Equal(t, int64(1), samples[0].Enqueued)
+		assert.Equal(t, int64(2), samples[1].Enqueued)
+		assert.Equal(t, int64(42), samples[0].MinutesCurrent)
+		assert.False(t, samples[0].Time.After(samples[1].Time))
+	})
+
+	t.Run("skips failed samples", func(t *testing.T) {
+		var calls atomic.Int64
+		fetch := func(ctx context.Context) (client.BuildAccountResponse, client.Meta, error) {
+			calls.Add(1)
+			return client.BuildAccountResponse{}, client.Meta{}, errors.New("unavailable")
+		}
+
+		s := Start(fetch, 5*time.Millisecond, 10)
+		defer s.Close()
+
+		require.Eventually(t, func() bool { return calls.Load() >= 2 }, time.Second, time.Millisecond)
+		assert.Empty(t, s.Samples(time.Time{}, time.Time{}))
+	})
+}
+
+func TestSamples(t *testing.T) {
+	at := func(minute int) time.Time { return time.Date(2024, 1, 1, 0, minute, 0, 0, time.UTC) }
+	s := &Sampler{samples: make([]Sample, 3)}
+	for minute := 1; minute <= 4; minute++ {
+		s.add(Sample{Time: at(minute), Active: int64(minute)})
+	}
+
+	t.Run("drops the oldest samples once full", func(t *testing.T) {
+		samples := s.Samples(time.Time{}, time.Time{})
+		require.Len(t, samples, 3)
+		assert.Equal(t, []time.Time{at(2), at(3), at(4)}, []time.Time{samples[0].Time, samples[1].Time, samples[2].Time})
+	})
+
+	t.Run("returns the time range", func(t *testing.T) {
+		samples := s.Samples(at(3), at(3))
+		require.Len(t, samples, 1)
+		assert.Equal(t, int64(3), samples[0].Active)
+	})
+
+	t.Run("nil sampler has no samples", func(t *testing.T) {
+		var s *Sampler
+		assert.Empty(t, s.Samples(time.Time{}, time.Time{}))
+		s.Close()
+	})
+}
@@ -0,0 +1,271 @@
+package stream
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+var (
+	// maxBackoff caps the wait between polls of a site that keeps failing.
+	maxBackoff = 5 * time.Minute
+	// window is how far back a poll looks for deploys.
+	window = 24 * time.Hour
+	// lowBudget is the share of the rate limit below which polls wait for
+	// the window to reset, so streams never starve queries.
+	lowBudget = 0.1
+)
+
+const (
+	// subscriberBuffer is how many updates a slow subscriber can fall behind
+	// before updates to it are dropped.
+	subscriberBuffer = 16
+	// maxSeen bounds how many deploys a poller remembers, the oldest are
+	// forgotten first.
+	maxSeen = 500
+)
+
+// Hub shares one deploy poller per site between every stream subscribed to
+// the site. A poller starts with the first subscriber and stops when the
+// last one leaves. Only deploys that changed since the previous poll are
+// published.
+type Hub struct {
+	fetch     client.Doer[client.DeploysResponse]
+	rateLimit func() client.RateLimit
+	interval  time.Duration
+
+	mu      sync.Mutex
+	pollers map[string]*poller
+}
+
+type poller struct {
+	cancel      context.CancelFunc
+	subscribers map[chan client.DeploysResponse]struct{}
+	// seen holds the last published state of every deploy by id.
+	seen map[string]client.Deploy
+	// order keeps the ids of seen in the order Netlify returned them.
+	order []string
+}
+
+func NewHub(fetch client.Doer[client.DeploysResponse], rateLimit func() client.RateLimit, interval time.Duration) *Hub {
+	return &Hub{
+		fetch:     fetch,
+		rateLimit: rateLimit,
+		interval:  interval,
+		pollers:   map[string]*poller{},
+	}
+}
+
+// Subscribe returns the updates of a site, starting with the deploys already
+// known. Call unsubscribe once done reading.
+func (h *Hub) Subscribe(siteId string) (updates <-chan client.DeploysResponse, unsubscribe func()) {
+	ch := make(chan client.DeploysResponse, subscriberBuffer)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p, ok := h.pollers[siteId]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		p = &poller{
+			cancel:      cancel,
+			subscribers: map[chan client.DeploysResponse]struct{}{},
+			seen:        map[string]client.Deploy{},
+		}
+		h.pollers[siteId] = p
+
+		go h.poll(ctx, siteId)
+	}
+
+	p.subscribers[ch] = struct{}{}
+	if snapshot := p.snapshot(); len(snapshot) > 0 {
+		ch <- snapshot
+	}
+
+	return ch, func() { h.unsubscribe(siteId, ch) }
+}
+
+func (h *Hub) unsubscribe(siteId string, ch chan client.DeploysResponse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p, ok := h.pollers[siteId]
+	if !ok {
+		return
+	}
+
+	delete(p.subscribers, ch)
+	if len(p.subscribers) == 0 {
+		p.cancel()
+		delete(h.pollers, siteId)
+	}
+}
+
+// Publish sends the deploys of a site that changed to its subscribers. It is
+// called by the pollers and can be fed by other sources such as webhooks.
+func (h *Hub) Publish(siteId string, deploys client.DeploysResponse) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p, ok := h.pollers[siteId]
+	if !ok {
+		return
+	}
+
+	changed := p.update(deploys)
+	if len(changed) == 0 {
+		return
+	}
+
+	for ch := range p.subscribers {
+		select {
+		case ch <- changed:
+		default:
+			backend.Logger.Warn("dropping deploy update for slow stream subscriber", "siteId", siteId)
+		}
+	}
+}
+
+// Close stops every poller.
+func (h *Hub) Close() {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for siteId, p := range h.pollers {
+		p.cancel()
+		delete(h.pollers, siteId)
+	}
+}
+
+func (h *Hub) poll(ctx context.Context, siteId string) {
+	failures := 0
+	for {
+		deploys, _, err := h.fetch(client.WithSince(client.NoCache(ctx), time.Now().Add(-window)), siteId)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			failures++
+			backend.Logger.Warn("failed to poll deploys", "siteId", siteId, "failures", failures, "err", err.Error())
+		default:
+			failures = 0
+			h.Publish(siteId, deploys)
+		}
+
+		timer := time.NewTimer(h.nextPoll(failures))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// nextPoll waits the interval, doubled for every consecutive failure, and
+// until the rate limit window resets when little of the budget is left.
+func (h *Hub) nextPoll(failures int) time.Duration {
+	wait := h.interval
+	for i := 0; i < failures && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	wait = min(wait, maxBackoff)
+
+	if h.rateLimit == nil {
+		return wait
+	}
+
+	state := h.rateLimit()
+	if state.Limit > 0 && float64(state.Remaining) < float64(state.Limit)*lowBudget {
+		wait = max(wait, min(time.Until(state.Reset), maxBackoff))
+	}
+
+	return wait
+}
+
+// update records deploys and returns the ones that are new or changed.
+func (p *poller) update(deploys client.DeploysResponse) client.DeploysResponse {
+	changed := client.DeploysResponse{}
+	for _, deploy := range deploys {
+		deploy = inUTC(deploy)
+		previous, ok := p.seen[deploy.ID]
+		if ok && previous == deploy {
+			continue
+		}
+
+		if !ok {
+			p.order = append(p.order, deploy.ID)
+		}
+
+		p.seen[deploy.ID] = deploy
+		changed = append(changed, deploy)
+	}
+
+	for len(p.order) > maxSeen {
+		delete(p.seen, p.order[0])
+		p.order = p.order[1:]
+	}
+
+	return changed
+}
+
+// inUTC normalizes the times of a deploy so unchanged deploys compare equal
+// whatever offset they were reported in.
+func inUTC(deploy client.Deploy) client.Deploy {
+	for _, t := range []*time.Time{&deploy.CreatedAt, &deploy.UpdatedAt, &deploy.PublishedAt, &deploy.ExpiresAt} {
+		*t = t.UTC()
+	}
+
+	return deploy
+}
+
+func (p *poller) snapshot() client.DeploysResponse {
+	deploys := make(client.DeploysResponse, 0, len(p.order))
+	for _, id := range p.order {
+		deploys = append(deploys, p.seen[id])
+	}
+
+	return deploys
+}
+
+// deploysPrefix starts the channel path of the deploys of a site.
+const deploysPrefix = "deploys/"
+
+// DeploysPath returns the channel path streaming the deploys of a site, for
+// a query with the options registered under optionsKey when not empty.
+func DeploysPath(siteId string, optionsKey string) string {
+	if optionsKey == "" {
+		return deploysPrefix + siteId
+	}
+
+	return deploysPrefix + siteId + "/" + optionsKey
+}
+
+// ParseDeploysPath returns the site a deploys channel path streams and the
+// key of the options of its query.
+func ParseDeploysPath(path string) (siteId string, optionsKey string, ok bool) {
+	rest, ok := strings.CutPrefix(path, deploysPrefix)
+	if !ok {
+		return "", "", false
+	}
+
+	siteId, options, hasOptions := strings.Cut(rest, "/")
+	if siteId == "" || (hasOptions && (options == "" || strings.Contains(options, "/"))) {
+		return "", "", false
+	}
+
+	return siteId, options, true
+}
@@ -0,0 +1,144 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/netlify-datasource/pkg/plugin/client"
+)
+
+// fakeSite serves the deploys of a site to the hub and counts the polls.
+type fakeSite struct {
+	mu      sync.Mutex
+	deploys client.DeploysResponse
+	polls   atomic.Int32
+}
+
+func (f *fakeSite) fetch(ctx context.Context, siteId string) (client.DeploysResponse, client.Meta, error) {
+	f.polls.Add(1)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append(client.DeploysResponse{}, f.deploys...), client.Meta{}, nil
+}
+
+func (f *fakeSite) set(deploys ...client.Deploy) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.deploys = deploys
+}
+
+func receive(t *testing.T, updates <-chan client.DeploysResponse) client.DeploysResponse {
+	t.Helper()
+
+	select {
+	case deploys := <-updates:
+		return deploys
+	case <-time.After(time.Second):
+		require.FailNow(t, "no update received")
+		return nil
+	}
+}
+
+func TestHub(t *testing.T) {
+	t.Run("publishes changed deploys to every subscriber", func(t *testing.T) {
+		site := &fakeSite{}
+		site.set(client.Deploy{ID: "deploy-1", State: "building"})
+		hub := NewHub(site.fetch, nil, 5*time.Millisecond)
+		defer hub.Close()
+
+		first, unsubscribeFirst := hub.Subscribe("my-site")
+		defer unsubscribeFirst()
+		assert.Equal(t, "building", receive(t, first)[0].State)
+
+		second, unsubscribeSecond := hub.Subscribe("my-site")
+		defer unsubscribeSecond()
+		// late subscribers start from the known deploys
+		assert.Equal(t, "building", receive(t, second)[0].State)
+
+		site.set(client.Deploy{ID: "deploy-2", State: "enqueued"}, client.Deploy{ID: "deploy-1", State: "ready"})
+
+		for _, updates := range []<-chan client.DeploysResponse{first, second} {
+			deploys := receive(t, updates)
+			require.Len(t, deploys, 2)
+			assert.Equal(t, "enqueued", deploys[0].State)
+			assert.Equal(t, "ready", deploys[1].State)
+		}
+
+		site.set(client.Deploy{ID: "deploy-2", State: "building"}, client.Deploy{ID: "deploy-1", State: "ready"})
+		deploys := receive(t, first)
+		require.Len(t, deploys, 1)
+		assert.Equal(t, "deploy-2", deploys[0].ID)
+	})
+
+	t.Run("stops polling when the last subscriber leaves", func(t *testing.T) {
+		site := &fakeSite{}
+		hub := NewHub(site.fetch, nil, 5*time.Millisecond)
+		defer hub.Close()
+
+		_, unsubscribeFirst := hub.Subscribe("my-site")
+		_, unsubscribeSecond := hub.Subscribe("my-site")
+		assert.Len(t, hub.pollers, 1)
+
+		unsubscribeFirst()
+		assert.Len(t, hub.pollers, 1)
+
+		unsubscribeSecond()
+		assert.Empty(t, hub.pollers)
+
+		time.Sleep(20 * time.Millisecond)
+		polls := site.polls.Load()
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, polls, site.polls.Load())
+	})
+}
+
+func TestNextPoll(t *testing.T) {
+	hub := NewHub(nil, nil, 10*time.Second)
+	assert.Equal(t, 10*time.Second, hub.nextPoll(0))
+	assert.Equal(t, 40*time.Second, hub.nextPoll(2))
+	assert.Equal(t, maxBackoff, hub.nextPoll(20))
+
+	hub.rateLimit = func() client.RateLimit {
+		return client.RateLimit{Limit: 500, Remaining: 10, Reset: time.Now().Add(time.Minute)}
+	}
+	assert.Greater(t, hub.nextPoll(0), 50*time.Second)
+}
+
+func TestParseDeploysPath(t *testing.T) {
+	siteId, options, ok := ParseDeploysPath(DeploysPath("my-site", ""))
+	assert.True(t, ok)
+	assert.Equal(t, "my-site", siteId)
+	assert.Empty(t, options)
+
+	siteId, options, ok = ParseDeploysPath(DeploysPath("my-site", "0123abcd"))
+	assert.True(t, ok)
+	assert.Equal(t, "my-site", siteId)
+	assert.Equal(t, "0123abcd", options)
+
+	for _, path := range []string{"deploys/", "builds/my-site", "deploys/my-site/", "deploys/my-site/options/extra"} {
+		_, _, ok := ParseDeploysPath(path)
+		assert.False(t, ok, path)
+	}
+}
+
+func TestRegisterOptions(t *testing.T) {
+	key := RegisterOptions([]byte(`{"filters":[{"field":"context","operator":"==","value":"production"}]}`))
+	assert.Equal(t, key, RegisterOptions([]byte(`{"filters":[{"field":"context","operator":"==","value":"production"}]}`)))
+	assert.NotEqual(t, key, RegisterOptions([]byte(`{"selectedFields":["ID"]}`)))
+
+	encoded, ok := Options(key)
+	assert.True(t, ok)
+	assert.Contains(t, string(encoded), "production")
+
+	_, ok = Options("unknown")
+	assert.False(t, ok)
+}
@@ -0,0 +1,54 @@
+package stream
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// maxOptions bounds how many query options are registered, the oldest are
+// forgotten first.
+const maxOptions = 1000
+
+// The options of live queries are registered for the whole process, channel
+// paths are too short to carry them. A new instance of the data source runs
+// the streams of the old one with the same options.
+var (
+	optionsMu    sync.Mutex
+	options      = map[string][]byte{}
+	optionsOrder []string
+)
+
+// RegisterOptions keeps the encoded options of a live query and returns the
+// key its channel path names them by. The key is a hash of the options, the
+// same query always registers under the same key.
+func RegisterOptions(encoded []byte) string {
+	sum := sha256.Sum256(encoded)
+	key := hex.EncodeToString(sum[:8])
+
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+
+	if _, ok := options[key]; ok {
+		return key
+	}
+
+	if len(optionsOrder) >= maxOptions {
+		delete(options, optionsOrder[0])
+		optionsOrder = optionsOrder[1:]
+	}
+
+	options[key] = encoded
+	optionsOrder = append(optionsOrder, key)
+
+	return key
+}
+
+// Options returns the options registered under key.
+func Options(key string) ([]byte, bool) {
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+
+	encoded, ok := options[key]
+	return encoded, ok
+}
@@ -1,8 +1,9 @@
-import React, { ChangeEvent } from 'react';
-import { InlineField, Input, SecretInput, useStyles2 } from '@grafana/ui';
+import React, { ChangeEvent, FocusEvent } from 'react';
+import { InlineField, InlineSwitch, Input, SecretInput, SecureSocksProxySettings, useStyles2 } from '@grafana/ui';
 import { DataSourcePluginOptionsEditorProps, GrafanaTheme2 } from '@grafana/data';
 import { NetlifyDataSourceOptions, NetlifySecureJsonData } from '../types';
-import { DataSourceDescription, ConfigSection } from '@grafana/experimental';
+import { config } from '@grafana/runtime';
+import { AdvancedHttpSettings, Auth, AuthMethod, ConfigSection, convertLegacyAuthProps, DataSourceDescription } from '@grafana/experimental';
 import { css } from '@emotion/css';
 
 interface Props extends DataSourcePluginOptionsEditorProps<NetlifyDataSourceOptions> { }
@@ -27,17 +28,83 @@ export function ConfigEditor(props: Props) {
     onOptionsChange({ ...options, jsonData });
   };
 
+  const onBaseUrlChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const jsonData = {
+      ...options.jsonData,
+      baseUrl: event.target.value,
+    };
+    onOptionsChange({ ...options, jsonData });
+  };
+
+  const onEnableHistoryChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const jsonData = {
+      ...options.jsonData,
+      enableHistory: event.currentTarget.checked,
+    };
+    onOptionsChange({ ...options, jsonData });
+  };
+
+  const onRedactFieldsChange = (event: FocusEvent<HTMLInputElement>) => {
+    const redactFields = event.target.value.split(',').map((field) => field.trim()).filter((field) => field !== '');
+    const jsonData = {
+      ...options.jsonData,
+      redactFields: redactFields.length > 0 ? redactFields : undefined,
+    };
+    onOptionsChange({ ...options, jsonData });
+  };
+
+  const onDisableRedactionChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const jsonData = {
+      ...options.jsonData,
+      disableRedaction: event.currentTarget.checked,
+    };
+    onOptionsChange({ ...options, jsonData });
+  };
+
+  const onNumberChange = (key: keyof NetlifyDataSourceOptions) => (event: ChangeEvent<HTMLInputElement>) => {
+    const value = parseInt(event.target.value, 10);
+    const jsonData = {
+      ...options.jsonData,
+      [key]: isNaN(value) ? undefined : value,
+    };
+    onOptionsChange({ ...options, jsonData });
+  };
 
   // Secure field (only sent to the backend)
   const onAPIKeyChange = (event: ChangeEvent<HTMLInputElement>) => {
     onOptionsChange({
       ...options,
       secureJsonData: {
+        ...options.secureJsonData,
         accessToken: event.target.value,
       },
     });
   };
 
+  const onWebhookSecretChange = (event: ChangeEvent<HTMLInputElement>) => {
+    onOptionsChange({
+      ...options,
+      secureJsonData: {
+        ...options.secureJsonData,
+        webhookSecret: event.target.value,
+      },
+    });
+  };
+
+  const onResetWebhookSecret = () => {
+    onOptionsChange({
+      ...options,
+      secureJsonFields: {
+        ...options.secureJsonFields,
+        webhookSecret: false,
+      },
+      secureJsonData: {
+        ...options.secureJsonData,
+        webhookSecret: '',
+      },
+    });
+  };
+
   const onResetAPIKey = () => {
     onOptionsChange({
       ...options,
@@ -62,7 +129,7 @@ export function ConfigEditor(props: Props) {
       <hr className={styles.break} />
 
       <ConfigSection
-        title="Authentication"
+        title="Netlify access"
         description="Provide the Access Token the grafana plugin will use to authenicate with the Netlify Api. "
       >
         <InlineField label="Access Token" labelWidth={20} tooltip="Netlify Access token found in the User Settings -> Applications in Netlify">
@@ -76,6 +143,38 @@ export function ConfigEditor(props: Props) {
             onChange={onAPIKeyChange}
           />
         </InlineField>
+        <InlineField label="Webhook Secret" labelWidth={20} tooltip="JWS secret of the Netlify outgoing deploy notifications posted to the webhooks/netlify resource, webhooks are rejected while empty">
+          <SecretInput
+            isConfigured={(secureJsonFields && secureJsonFields.webhookSecret) as boolean}
+            value={secureJsonData.webhookSecret || ''}
+            placeholder="Webhook Secret"
+            width={40}
+            onReset={onResetWebhookSecret}
+            onChange={onWebhookSecretChange}
+          />
+        </InlineField>
+      </ConfigSection>
+
+      <hr className={styles.break} />
+
+      {/* the access token authenticates with Netlify, TLS and custom headers still apply */}
+      <Auth
+        {...convertLegacyAuthProps({ config: options, onChange: onOptionsChange })}
+        visibleMethods={[AuthMethod.NoAuth]}
+      />
+
+      <hr className={styles.break} />
+
+      <ConfigSection
+        title="HTTP settings"
+        description="Timeout and cookies of the requests to the Netlify API, a proxy is picked up from the environment of Grafana"
+        isCollapsible
+        isInitiallyOpen={false}
+      >
+        <AdvancedHttpSettings config={options} onChange={onOptionsChange} />
+        {config.secureSocksDSProxyEnabled && (
+          <SecureSocksProxySettings options={options} onOptionsChange={onOptionsChange} />
+        )}
       </ConfigSection>
 
       <hr className={styles.break} />
@@ -102,6 +201,118 @@ export function ConfigEditor(props: Props) {
             width={40}
           />
         </InlineField>
+        <InlineField label="API Base URL" labelWidth={20} tooltip="Netlify API root, change it to route requests through a proxy or a local Netlify stand-in">
+          <Input
+            onChange={onBaseUrlChange}
+            value={jsonData.baseUrl || ''}
+            placeholder="https://api.netlify.com/api/v1"
+            width={40}
+          />
+        </InlineField>
+        <InlineField label="Page size" labelWidth={20} tooltip="Items requested per page from paginated endpoints, at most 100">
+          <Input
+            type="number"
+            onChange={onNumberChange('pageSize')}
+            value={jsonData.pageSize ?? ''}
+            placeholder="100"
+            width={40}
+          />
+        </InlineField>
+        <InlineField label="Max pages" labelWidth={20} tooltip="Maximum number of pages fetched per list request, results beyond it are capped">
+          <Input
+            type="number"
+            onChange={onNumberChange('maxPages')}
+            value={jsonData.maxPages ?? ''}
+            placeholder="10"
+            width={40}
+          />
+        </InlineField>
+        <InlineField label="Sample interval" labelWidth={20} tooltip="Seconds between samples of the account build queue and minutes, negative values disable sampling">
+          <Input
+            type="number"
+            onChange={onNumberChange('sampleInterval')}
+            value={jsonData.sampleInterval ?? ''}
+            placeholder="60"
+            width={40}
+          />
+        </InlineField>
+        <InlineField label="Sample size" labelWidth={20} tooltip="Number of build status samples kept in memory">
+          <Input
+            type="number"
+            onChange={onNumberChange('sampleSize')}
+            value={jsonData.sampleSize ?? ''}
+            placeholder="10080"
+            width={40}
+          />
+        </InlineField>
+        <InlineField label="Redact fields" labelWidth={20} tooltip="Comma separated fields redacted from every entity on top of the built-in sensitive fields such as site passwords, deploy hooks and build environment">
+          <Input
+            onBlur={onRedactFieldsChange}
+            defaultValue={(jsonData.redactFields ?? []).join(', ')}
+            placeholder="notification_email, data.email"
+            width={40}
+          />
+        </InlineField>
+        <InlineField label="Disable redaction" labelWidth={20} tooltip="Show sensitive fields to every viewer of dashboards using this data source">
+          <InlineSwitch value={jsonData.disableRedaction ?? false} onChange={onDisableRedactionChange} />
+        </InlineField>
+        <InlineField label="Keep history" labelWidth={20} tooltip="Keep deploys and builds in the plugin data directory of Grafana to query history Netlify no longer lists">
+          <InlineSwitch value={jsonData.enableHistory ?? false} onChange={onEnableHistoryChange} />
+        </InlineField>
+        <InlineField label="Max retries" labelWidth={20} tooltip="How often rate limited (429) or failing (5xx) requests are retried, 0 disables retries">
+          <Input
+            type="number"
+            onChange={onNumberChange('maxRetries')}
+            value={jsonData.maxRetries ?? ''}
+            placeholder="3"
+            width={40}
+          />
+        </InlineField>
+        <InlineField label="Request timeout" labelWidth={20} tooltip="Timeout in seconds of a single request to the Netlify API, overrides the timeout of the HTTP settings when set">
+          <Input
+            type="number"
+            onChange={onNumberChange('requestTimeout')}
+            value={jsonData.requestTimeout ?? ''}
+            placeholder="HTTP settings timeout"
+            width={40}
+          />
+        </InlineField>
+        <InlineField label="Query timeout" labelWidth={20} tooltip="Timeout in seconds of a whole query including pagination, retries and multi-site fan-out">
+          <Input
+            type="number"
+            onChange={onNumberChange('queryTimeout')}
+            value={jsonData.queryTimeout ?? ''}
+            placeholder="120"
+            width={40}
+          />
+        </InlineField>
+        <InlineField label="Concurrency" labelWidth={20} tooltip="How many sites a multi-site query requests from Netlify at the same time">
+          <Input
+            type="number"
+            onChange={onNumberChange('concurrency')}
+            value={jsonData.concurrency ?? ''}
+            placeholder="4"
+            width={40}
+          />
+        </InlineField>
+        <InlineField label="Cache size" labelWidth={20} tooltip="Number of Netlify responses kept in the in-memory cache">
+          <Input
+            type="number"
+            onChange={onNumberChange('cacheSize')}
+            value={jsonData.cacheSize ?? ''}
+            placeholder="500"
+            width={40}
+          />
+        </InlineField>
+        <InlineField label="Stream interval" labelWidth={20} tooltip="Seconds between polls of a site while a live deploy stream is open">
+          <Input
+            type="number"
+            onChange={onNumberChange('streamInterval')}
+            value={jsonData.streamInterval ?? ''}
+            placeholder="10"
+            width={40}
+          />
+        </InlineField>
       </ConfigSection>
     </div>
   );
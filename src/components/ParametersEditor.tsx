@@ -1,20 +1,112 @@
-import { InlineField, Input } from "@grafana/ui"
+import { Button, HorizontalGroup, InlineField, Input, Select } from "@grafana/ui"
 import { QueryOptionGroup } from "./QueryOptionsGroup"
 import React from "react"
+import { NetlifyFilter, NetlifyFilterOperator, NetlifyQuery } from "types"
 
 type Props = {
-
+    entity?: string;
+    query: NetlifyQuery;
+    onChange: (query: NetlifyQuery) => void;
 }
 
-export const ParametersEditor = (props: Props) => {
+const operator_options: Array<{ label: string, value: NetlifyFilterOperator, description: string }> = [
+    { label: '==', value: '==', description: 'Equals' },
+    { label: '!=', value: '!=', description: 'Not equals' },
+    { label: '=~', value: '=~', description: 'Matches the regular expression' },
+    { label: 'in', value: 'in', description: 'Is one of the comma separated values' },
+    { label: '>', value: '>', description: 'Greater than, for numbers and RFC 3339 times' },
+    { label: '>=', value: '>=', description: 'Greater than or equal' },
+    { label: '<', value: '<', description: 'Less than' },
+    { label: '<=', value: '<=', description: 'Less than or equal' },
+];
+
+const direction_options: Array<{ label: string, value: 'asc' | 'desc' }> = [
+    { label: 'Ascending', value: 'asc' },
+    { label: 'Descending', value: 'desc' },
+];
+
+const aggregate_options = [
+    { label: 'Table', value: '', description: 'Return the rows' },
+    { label: 'Time series', value: 'multi', description: 'Count rows per interval, one series per group' },
+    { label: 'Time series (long)', value: 'long', description: 'Count rows per interval in a single long frame' },
+];
+
+const aggregated_entities = ['builds', 'deployments', 'form-submissions']
+
+const numberOrUndefined = (value: string) => (value === '' ? undefined : Number(value))
+
+export const ParametersEditor = ({ entity, query, onChange }: Props) => {
+    const filters = query.filters ?? []
+
+    const updateFilter = (index: number, update: Partial<NetlifyFilter>) => {
+        onChange({ ...query, filters: filters.map((f, i) => (i === index ? { ...f, ...update } : f)) })
+    }
+
+    const addFilter = () => {
+        onChange({ ...query, filters: [...filters, { field: '', operator: '==', value: '' }] })
+    }
+
+    const removeFilter = (index: number) => {
+        onChange({ ...query, filters: filters.filter((_, i) => i !== index) })
+    }
 
     return (
-        <QueryOptionGroup title="Optional Parameters">
-            <InlineField label="Limit" labelWidth={20}
-                tooltip="Name of the field in the response to read data from"
+        <QueryOptionGroup title="Optional Parameters" defaultIsOpen={filters.length > 0 || !!query.orderBy || !!query.limit || !!query.aggregate}>
+            <InlineField label="Order by" labelWidth={20}
+                tooltip="Field the rows of every site are sorted by, e.g. CreatedAt"
                 grow>
-                <Input name="hello" />
+                <Input value={query.orderBy ?? ''} onChange={(e) => onChange({ ...query, orderBy: e.currentTarget.value })} />
             </InlineField>
+            <InlineField label="Direction" labelWidth={20}>
+                <Select
+                    options={direction_options}
+                    value={query.direction ?? 'asc'}
+                    onChange={(option) => onChange({ ...query, direction: option.value })}
+                />
+            </InlineField>
+            {/* time series count every row, offset and limit only apply to tables */}
+            {!query.aggregate && (
+                <>
+                    <InlineField label="Offset" labelWidth={20} tooltip="Number of rows to skip after sorting">
+                        <Input type="number" min={0} value={query.offset ?? ''} onChange={(e) => onChange({ ...query, offset: numberOrUndefined(e.currentTarget.value) })} />
+                    </InlineField>
+                    <InlineField label="Limit" labelWidth={20} tooltip="Maximum number of rows to return, empty returns every row">
+                        <Input type="number" min={0} value={query.limit ?? ''} onChange={(e) => onChange({ ...query, limit: numberOrUndefined(e.currentTarget.value) })} />
+                    </InlineField>
+                </>
+            )}
+            {aggregated_entities.includes(entity ?? '') && (
+                <InlineField label="Format" labelWidth={20} tooltip="Count the rows created per interval to chart them">
+                    <Select
+                        options={aggregate_options}
+                        value={query.aggregate?.format ?? ''}
+                        onChange={(option) => onChange(option.value
+                            ? { ...query, aggregate: { ...query.aggregate, format: option.value as 'multi' | 'long' }, offset: undefined, limit: undefined }
+                            : { ...query, aggregate: undefined })}
+                    />
+                </InlineField>
+            )}
+            {query.aggregate && (
+                <InlineField label="Group by" labelWidth={20} tooltip="Field the series are split by, e.g. State, Branch, Context or site">
+                    <Input value={query.aggregate.groupBy ?? ''} onChange={(e) => onChange({ ...query, aggregate: { ...query.aggregate, groupBy: e.currentTarget.value } })} />
+                </InlineField>
+            )}
+            {filters.map((filter, index) => (
+                <HorizontalGroup key={index}>
+                    <InlineField label="Filter" labelWidth={20} tooltip="Only rows matching every filter are returned, e.g. State == error">
+                        <Input placeholder="Field" value={filter.field} onChange={(e) => updateFilter(index, { field: e.currentTarget.value })} />
+                    </InlineField>
+                    <Select
+                        width={10}
+                        options={operator_options}
+                        value={filter.operator}
+                        onChange={(option) => updateFilter(index, { operator: option.value! })}
+                    />
+                    <Input placeholder="Value" value={filter.value} onChange={(e) => updateFilter(index, { value: e.currentTarget.value })} />
+                    <Button variant="secondary" icon="trash-alt" aria-label="Remove filter" onClick={() => removeFilter(index)} />
+                </HorizontalGroup>
+            ))}
+            <Button variant="secondary" icon="plus" onClick={addFilter}>Add filter</Button>
         </QueryOptionGroup>
     )
-}
\ No newline at end of file
+}
@@ -1,5 +1,5 @@
 import React, { useCallback, useEffect, useState } from 'react';
-import { HorizontalGroup, InlineField, Input, Select } from '@grafana/ui';
+import { HorizontalGroup, InlineField, InlineSwitch, Input, Select } from '@grafana/ui';
 import { QueryEditorProps, SelectableValue } from '@grafana/data';
 import { DataSource } from '../datasource';
 import { NetlifyDataSourceOptions, NetlifyQuery } from '../types';
@@ -16,12 +16,21 @@ const entity_options = [
   { label: 'Form Submissions', value: 'form-submissions', description: 'Query for list of form submissions by site id' },
   { label: 'Sites', value: 'sites', description: 'Query for list of owned Sites' },
   { label: 'Accounts', value: 'accounts', description: 'Query for list of Accounts' },
+  { label: 'DORA metrics', value: 'dora', description: 'Deployment frequency, lead time, change failure rate (failed and rolled back deploys) and time to restore by site id' },
+  { label: 'Deploy annotations', value: 'deploy-annotations', description: 'Deploys by site id as annotation events' },
+  { label: 'Build minutes forecast', value: 'build-minutes-forecast', description: 'Projected build minutes, overage and quota exhaustion by the end of the billing period' },
+  { label: 'Build minutes by site', value: 'build-minutes-by-site', description: 'Build minutes of the billing period, or of the time range without an account id, ranked by site id, context and branch' },
 ];
 
-const entities_requiring_site_id = ['builds', 'deployments', 'forms', 'form-submissions']
+const entities_requiring_site_id = ['builds', 'deployments', 'forms', 'form-submissions', 'dora', 'deploy-annotations', 'build-minutes-by-site']
 
 const default_site_id = { label: 'Default Site Id', value: '' }
 
+const error_mode_options = [
+  { label: 'Partial', value: 'partial', description: 'Return the sites that succeeded and a warning for each failed site' },
+  { label: 'Strict', value: 'strict', description: 'Fail the query when any site fails' },
+];
+
 export function QueryEditor({ query, onChange, onRunQuery, datasource, data, ...rest }: Props) {
   console.log({ query, onChange, onRunQuery, datasource, ...rest })
   const [_, setSiteIdOptions] = useState([default_site_id])
@@ -40,6 +49,11 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource, data, ...
   }, [datasource])
 
 
+  const handleErrorModeChange = (value: SelectableValue<string>) => {
+    onChange({ ...query, errorMode: value.value as NetlifyQuery['errorMode'] });
+    onRunQuery();
+  }
+
   const handleSiteIdUpdates = (value: string) => {
     console.log('handleSiteIdUpdates', { v: value })
     onChange({ ...query, siteId: value });
@@ -95,15 +109,62 @@ export function QueryEditor({ query, onChange, onRunQuery, datasource, data, ...
             handleSiteIdUpdates(e.currentTarget.value)
           }} />
         </InlineField>
+      )}
+      {entities_requiring_site_id.includes(entity ?? '') && (
+        <InlineField
+          label="On site error"
+          grow
+          labelWidth={20}
+          tooltip="How a multi-site query handles sites that fail"
+        >
+          <Select
+            options={error_mode_options}
+            value={query.errorMode ?? 'partial'}
+            onChange={handleErrorModeChange}
+          />
+        </InlineField>
 
       )}
+      {entity === 'deployments' && (
+        <InlineField
+          label="Live"
+          labelWidth={20}
+          tooltip="Stream deploys of a single site as they move through their states"
+        >
+          <InlineSwitch
+            value={query.live ?? false}
+            onChange={(e) => {
+              onChange({ ...query, live: e.currentTarget.checked });
+              onRunQuery();
+            }}
+          />
+        </InlineField>
+      )}
+      {entity === 'builds-account' && (
+        <InlineField
+          label="Time series"
+          labelWidth={20}
+          tooltip="Return the build queue and minutes sampled over the time range instead of the current status"
+        >
+          <InlineSwitch
+            value={query.series ?? false}
+            onChange={(e) => {
+              onChange({ ...query, series: e.currentTarget.checked });
+              onRunQuery();
+            }}
+          />
+        </InlineField>
+      )}
       {/* </HorizontalGroup> */}
 
       <HorizontalGroup>
         <ParametersEditor entity={entity} query={query} onChange={onChange} />
       </HorizontalGroup>
 
-      <ParsingOptionsEditor query={query} onRunQuery={onRunQuery} data={data} onChange={onChange} editorType='query' actionConfig={{}} />
+      {/* annotations have fixed fields */}
+      {entity !== 'deploy-annotations' && (
+        <ParsingOptionsEditor query={query} onRunQuery={onRunQuery} data={data} onChange={onChange} editorType='query' actionConfig={{}} />
+      )}
     </>
   );
 }
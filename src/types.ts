@@ -7,6 +7,33 @@ export interface NetlifyQuery extends DataQuery {
   parsingOptions?: {
     selectedFields: string[]
   }
+  errorMode?: 'partial' | 'strict';
+  /** Rows are kept when they match every filter */
+  filters?: NetlifyFilter[];
+  /** Field the rows merged from every site are sorted by */
+  orderBy?: string;
+  direction?: 'asc' | 'desc';
+  offset?: number;
+  limit?: number;
+  /** Returns the number of rows created per interval instead of a table */
+  aggregate?: {
+    /** Field the series are split by, e.g. state, branch, context or site */
+    groupBy?: string;
+    format?: 'multi' | 'long';
+  };
+  /** Streams the deploys of a single site as they change */
+  live?: boolean;
+  /** Returns the sampled build status of builds-account over the time range */
+  series?: boolean;
+}
+
+export type NetlifyFilterOperator = '==' | '!=' | '=~' | 'in' | '>' | '>=' | '<' | '<=';
+
+export interface NetlifyFilter {
+  field: string;
+  operator: NetlifyFilterOperator;
+  /** Comma separated list of values for the in operator */
+  value: string;
 }
 
 /**
@@ -14,8 +41,38 @@ export interface NetlifyQuery extends DataQuery {
  */
 export interface NetlifyDataSourceOptions extends DataSourceJsonData {
   path?: string;
+  baseUrl?: string;
   accountId?: string;
   siteId?: string;
+  pageSize?: number;
+  maxPages?: number;
+  maxRetries?: number;
+  requestTimeout?: number;
+  queryTimeout?: number;
+  concurrency?: number;
+  /** Seconds responses are cached per entity, negative values disable caching */
+  cacheTTL?: {
+    sites?: number;
+    deploys?: number;
+    builds?: number;
+    forms?: number;
+    submissions?: number;
+    accounts?: number;
+    buildStatus?: number;
+  };
+  cacheSize?: number;
+  /** Seconds between polls of a site while a live deploy stream is open */
+  streamInterval?: number;
+  /** Keeps the deploy and build history in the plugin data directory */
+  enableHistory?: boolean;
+  /** Seconds between samples of the build status, negative values disable sampling */
+  sampleInterval?: number;
+  /** Number of build status samples kept */
+  sampleSize?: number;
+  /** Fields redacted from every entity on top of the built-in sensitive fields */
+  redactFields?: string[];
+  /** Shows the sensitive fields, only data source admins can change it */
+  disableRedaction?: boolean;
 }
 
 /**
@@ -23,4 +80,6 @@ export interface NetlifyDataSourceOptions extends DataSourceJsonData {
  */
 export interface NetlifySecureJsonData {
   accessToken?: string;
+  /** Secret Netlify signs webhook notifications with */
+  webhookSecret?: string;
 }